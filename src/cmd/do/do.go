@@ -6,12 +6,13 @@ import (
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/Samasource/jen/src/cmd/internal"
 	"github.com/Samasource/jen/src/internal/exec"
+	"github.com/Samasource/jen/src/internal/logging"
 	"github.com/spf13/cobra"
 )
 
 // New creates a cobra command
 func New(options *internal.Options) *cobra.Command {
-	return &cobra.Command{
+	c := &cobra.Command{
 		Use:   "do",
 		Short: "Executes an action from a template's spec.yaml",
 		Args:  cobra.RangeArgs(0, 1),
@@ -19,6 +20,9 @@ func New(options *internal.Options) *cobra.Command {
 			return run(options, args)
 		},
 	}
+	c.Flags().BoolVar(&options.DryRun, "dry-run", false, "only report files that would be rendered and commands that would be executed, without modifying anything")
+	c.Flags().StringVar(&options.Overwrite, "overwrite", "", "policy for existing files with different content, overriding that of render steps (overwrite, skip-existing, fail-on-existing or prompt)")
+	return c
 }
 
 func run(options *internal.Options, optionalActionName []string) error {
@@ -27,6 +31,12 @@ func run(options *internal.Options, optionalActionName []string) error {
 		return err
 	}
 
+	// Placeholders only matter when rendering, so other commands do not warn
+	// about those that may conflict with one another
+	for _, warning := range execContext.GetPlaceholders().Overlaps() {
+		logging.Warning("%s", warning)
+	}
+
 	// If action name not specified, prompt user to select it from list of available actions
 	actionName := ""
 	if len(optionalActionName) == 0 {
@@ -44,6 +54,9 @@ func run(options *internal.Options, optionalActionName []string) error {
 		return fmt.Errorf("action %q not found in spec file", actionName)
 	}
 
+	if execContext.IsDryRun() {
+		logging.Plan("Would enter action %q", actionName)
+	}
 	return action.Execute(execContext)
 }
 
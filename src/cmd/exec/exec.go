@@ -36,7 +36,11 @@ func run(options *internal.Options, args []string) error {
 		args = []string{script}
 	}
 
-	return shell.Execute(execContext.GetShellVars(true), "", strings.Join(args, " "))
+	env, err := execContext.GetShellVars(true)
+	if err != nil {
+		return err
+	}
+	return shell.Execute(env, "", strings.Join(args, " "))
 }
 
 func promptScript(context exec.Context) (string, error) {
@@ -3,6 +3,7 @@ package export
 import (
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/Samasource/jen/src/cmd/internal"
 	"github.com/spf13/cobra"
@@ -10,25 +11,36 @@ import (
 
 // New creates a cobra command
 func New(options *internal.Options) *cobra.Command {
-	return &cobra.Command{
+	var includeSecrets bool
+	c := &cobra.Command{
 		Use:   "export",
 		Short: `Outputs vars in "export VAR=value" format to be sourced using "$(jen export)"`,
 		Args:  cobra.NoArgs,
 		RunE: func(_ *cobra.Command, args []string) error {
-			return run(options, args)
+			return run(options, includeSecrets)
 		},
 	}
+	c.Flags().BoolVar(&includeSecrets, "secrets", false, "include secret variables")
+	return c
 }
 
-func run(options *internal.Options, args []string) error {
+func run(options *internal.Options, includeSecrets bool) error {
 	execContext, err := options.NewContext()
 	if err != nil {
 		return err
 	}
 
-	vars := execContext.GetShellVars(false)
+	secrets := execContext.GetSecrets()
+	vars, err := execContext.GetShellVars(false)
+	if err != nil {
+		return err
+	}
 	sort.Strings(vars)
 	for _, v := range vars {
+		name := strings.SplitN(v, "=", 2)[0]
+		if _, ok := secrets[name]; ok && !includeSecrets {
+			continue
+		}
 		fmt.Printf("export %s\n", v)
 	}
 	return nil
@@ -8,12 +8,17 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/Samasource/jen/src/internal/evaluation"
 	"github.com/Samasource/jen/src/internal/exec"
 	"github.com/Samasource/jen/src/internal/helpers"
+	"github.com/Samasource/jen/src/internal/helpers/conversion"
 	"github.com/Samasource/jen/src/internal/home"
 	"github.com/Samasource/jen/src/internal/logging"
 	"github.com/Samasource/jen/src/internal/project"
+	"github.com/Samasource/jen/src/internal/schema"
+	"github.com/Samasource/jen/src/internal/secrets"
 	"github.com/Samasource/jen/src/internal/spec"
+	"github.com/Samasource/jen/src/internal/vfs"
 )
 
 // Options represents all command line configurations
@@ -21,16 +26,56 @@ type Options struct {
 	TemplateName string
 	SkipConfirm  bool
 	VarOverrides []string
+	DryRun       bool
+	Overwrite    string
+	Strict       bool
+	Profile      string
+
+	// CreateProfile allows selecting a profile that does not exist yet in
+	// project file, which then gets created as variables are saved to it
+	CreateProfile bool
+}
+
+// profileVar is the name of env var specifying the active profile, when not
+// specified via command line. It is also passed to shell commands, so that
+// nested jen invocations use the same profile.
+const profileVar = "JEN_PROFILE"
+
+// getProfile returns the name of the active profile, if any
+func (o Options) getProfile() string {
+	if o.Profile != "" {
+		return o.Profile
+	}
+	return os.Getenv(profileVar)
+}
+
+// getProfileNames describes the profiles defined in given project, for error
+// reporting
+func getProfileNames(proj *project.Project) string {
+	if len(proj.Profiles) == 0 {
+		return "no profiles defined"
+	}
+	names := make([]string, 0, len(proj.Profiles))
+	for name := range proj.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return "available profiles: " + strings.Join(names, ", ")
 }
 
 // NewContext creates a context to be used for executing executables
 func (o Options) NewContext() (exec.Context, error) {
-	_, err := home.GetOrCloneRepo()
+	overwrite, err := evaluation.ParseOverwritePolicy(o.Overwrite)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = home.GetOrCloneRepo()
 	if err != nil {
 		return nil, err
 	}
 
-	proj, err := project.LoadOrCreate(o.TemplateName, o.SkipConfirm, o.VarOverrides)
+	proj, err := project.LoadOrCreate(o.TemplateName, o.SkipConfirm, o.DryRun)
 	if err != nil {
 		return nil, err
 	}
@@ -45,44 +90,120 @@ func (o Options) NewContext() (exec.Context, error) {
 		return nil, err
 	}
 
+	proj.Profile = o.getProfile()
+	if proj.Profile != "" {
+		if _, ok := proj.Profiles[proj.Profile]; !ok && !o.CreateProfile {
+			return nil, fmt.Errorf("unknown profile %q (%s), use --create-profile flag to create it", proj.Profile, getProfileNames(proj))
+		}
+		logging.Log("Using profile %q", proj.Profile)
+	}
+
+	// Variables in project file and set via command line must conform to
+	// schema, before any of them gets saved
+	if err := specification.Vars.Validate(proj.Vars); err != nil {
+		return nil, fmt.Errorf("validating project variables: %w", err)
+	}
+	for name, vars := range proj.Profiles {
+		if err := specification.Vars.Validate(vars); err != nil {
+			return nil, fmt.Errorf("validating variables of profile %q: %w", name, err)
+		}
+	}
+	secretOverrides, err := proj.ApplyOverrides(o.VarOverrides, specification.Vars)
+	if err != nil {
+		return nil, err
+	}
+
 	cloneSubDir, err := home.GetCloneSubDir()
 	if err != nil {
 		return nil, err
 	}
 
-	return context{
-		cloneSubDir: cloneSubDir,
-		templateDir: templateDir,
-		project:     proj,
-		spec:        *specification,
-	}, nil
+	// Template's own partials override those shared at root of templates repo
+	partials, err := evaluation.LoadPartials(cloneSubDir, templateDir)
+	if err != nil {
+		return nil, err
+	}
+
+	secretsFile, err := home.GetSecretsFile()
+	if err != nil {
+		return nil, err
+	}
+	secretStore, err := secrets.Load(secretsFile)
+	if err != nil {
+		return nil, err
+	}
+	secretStore.DryRun = o.DryRun
+
+	// Secrets are keyed by absolute project dir
+	absProjectDir, err := filepath.Abs(proj.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine project's absolute dir: %w", err)
+	}
+
+	c := context{
+		cloneSubDir:   cloneSubDir,
+		templateDir:   templateDir,
+		project:       proj,
+		absProjectDir: absProjectDir,
+		spec:          *specification,
+		partials:      partials,
+		dryRun:        o.DryRun,
+		overwrite:     overwrite,
+		strict:        o.Strict,
+		secrets:       secretStore,
+		computed:      &computedVars{},
+	}
+
+	// Secret variables set via command line go straight to secrets file
+	for name, value := range secretOverrides {
+		if err := c.SetSecret(name, value); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
 }
 
 // context contains all the information for implementing both the
 // exec.context and evaluation.context interfaces
 type context struct {
-	cloneSubDir string
-	templateDir string
-	project     *project.Project
-	spec        spec.Spec
+	cloneSubDir   string
+	templateDir   string
+	project       *project.Project
+	absProjectDir string
+	spec          spec.Spec
+	partials      []evaluation.Partial
+	dryRun        bool
+	overwrite     evaluation.OverwritePolicy
+	strict        bool
+	secrets       *secrets.Store
+	computed      *computedVars
 }
 
 // GetVars returns a dictionary of the project's variable names mapped to
-// their corresponding values. It does not include the process' env var.
-// Whenever you alter this map, you are responsible for later calling
+// their corresponding values, overlaid with those of active profile, if any.
+// It does not include the process' env var. Whenever you alter this map, you are responsible for later calling
 // SetVars() to save your changes back to the project file.
 func (c context) GetVars() map[string]interface{} {
-	clone := make(map[string]interface{})
-	for k, v := range c.project.Vars {
-		clone[k] = v
-	}
-	return clone
+	return c.project.GetVars()
 }
 
-// SetVars saves given variables in project file.
+// SetVars saves given variables in project file (or only in memory, in dry-run mode),
+// writing modified variables to active profile, if any.
 func (c context) SetVars(vars map[string]interface{}) error {
-	c.project.Vars = vars
-	return c.project.Save()
+	// Computed variables must be evaluated again from new values
+	*c.computed = computedVars{}
+	return c.project.SetVars(vars)
+}
+
+// GetProfile returns the name of the active profile, whose variables overlay
+// base ones, or an empty string if none.
+func (c context) GetProfile() string {
+	return c.project.Profile
+}
+
+// IsProfileVar returns whether given variable is defined by active profile.
+func (c context) IsProfileVar(name string) bool {
+	return c.project.IsProfileVar(name)
 }
 
 // IsVarOverriden returns whether given variable has been overriden via command
@@ -96,26 +217,115 @@ func (c context) IsVarOverriden(name string) bool {
 	return false
 }
 
+// GetSecrets returns the values of the project's secret variables, which are
+// saved in a per-user secrets file, rather than in project file. Env vars of
+// same names take precedence over saved values, unless those were set via
+// command line, and are also included when no value is saved.
+func (c context) GetSecrets() map[string]string {
+	values := c.secrets.Get(c.absProjectDir)
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	for name, v := range c.spec.Vars {
+		if _, ok := values[name]; !ok && v.Secret {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		if value, ok := os.LookupEnv(name); ok && !c.IsVarOverriden(name) {
+			values[name] = value
+		}
+	}
+	return values
+}
+
+// SetSecret saves the value of given secret variable in the per-user secrets
+// file (or only in memory, in dry-run mode).
+func (c context) SetSecret(name, value string) error {
+	return c.secrets.Set(c.absProjectDir, name, value)
+}
+
+// GetVarSchema returns the types and constraints of project variables declared
+// in template spec.
+func (c context) GetVarSchema() schema.Schema {
+	return c.spec.Vars
+}
+
 // GetPlaceholders returns a map of special placeholders that can be used instead
 // of go template expressions, for more lightweight templating, especially for the
 // project's name, which appears everywhere.
-func (c context) GetPlaceholders() map[string]string {
+func (c context) GetPlaceholders() evaluation.Placeholders {
 	return c.spec.Placeholders
 }
 
 // GetEvalVars returns a dictionary of the project's variable names mapped to
-// their corresponding values for evaluation purposes. It does not include the
-// process' env var.
-func (c context) GetEvalVars() map[string]interface{} {
-	vars := c.GetVars()
-	absProjectDir, err := filepath.Abs(c.project.Dir)
+// their corresponding values for evaluation purposes, including the template's
+// computed variables. It does not include the process' env var. It fails if
+// computed variables cannot be evaluated.
+func (c context) GetEvalVars() (map[string]interface{}, error) {
+	computed, err := c.getComputedVars()
 	if err != nil {
-		panic(fmt.Errorf("failed to determine project's absolute dir: %w", err))
+		return nil, err
 	}
-	vars["projectDirName"] = filepath.Base(absProjectDir)
+	vars := c.getBaseEvalVars()
+	for k, v := range computed {
+		vars[k] = v
+	}
+	return vars, nil
+}
+
+// getVarsWithDefaults returns the project's variables, completed with the
+// default values of missing ones declared in schema. Defaults are only applied
+// to this copy, so that they never get saved to project file.
+func (c context) getVarsWithDefaults() map[string]interface{} {
+	vars := c.GetVars()
+	c.spec.Vars.ApplyDefaults(vars)
+	return vars
+}
+
+// getBaseEvalVars returns the evaluation variables, excluding computed ones
+func (c context) getBaseEvalVars() map[string]interface{} {
+	vars := c.getVarsWithDefaults()
+	vars["projectDirName"] = filepath.Base(c.absProjectDir)
+	vars["profile"] = c.project.Profile
 	return vars
 }
 
+// computedVars caches the values of the template's computed variables, along
+// with the error that evaluating them returned, if any. It is shared by all
+// copies of a context and only invalidated when project variables change.
+type computedVars struct {
+	values map[string]interface{}
+	err    error
+	valid  bool
+}
+
+// getComputedVars returns the values of the template's computed variables,
+// evaluating them from current values of other variables, unless already done
+// since those last changed. Returned map must not be altered.
+func (c context) getComputedVars() (map[string]interface{}, error) {
+	if len(c.spec.Computed) == 0 {
+		return nil, nil
+	}
+	if !c.computed.valid {
+		c.computed.values, c.computed.err = c.spec.Computed.Eval(baseContext{c})
+		c.computed.valid = true
+	}
+	return c.computed.values, c.computed.err
+}
+
+// baseContext is a context whose evaluation variables exclude computed ones,
+// for evaluating those without recursion
+type baseContext struct {
+	context
+}
+
+// GetEvalVars returns the evaluation variables, excluding computed ones
+func (c baseContext) GetEvalVars() (map[string]interface{}, error) {
+	return c.getBaseEvalVars(), nil
+}
+
 // getBinDirs returns the list of bin dirs that actually exist
 func (c context) getBinDirs() []string {
 	binDirs := []string{
@@ -155,7 +365,13 @@ func (c context) GetScripts() ([]string, error) {
 // GetShellVars returns all env vars to be used when invoking shell commands,
 // including the current process' env vars, the project's vars and an augmented
 // PATH var including extra bin dirs.
-func (c context) GetShellVars(includeProcessVars bool) []string {
+func (c context) GetShellVars(includeProcessVars bool) ([]string, error) {
+	computed, err := c.getComputedVars()
+	if err != nil {
+		return nil, err
+	}
+	secretValues := c.GetSecrets()
+
 	// Add bin dirs to PATH env var
 	binDirs := c.getBinDirs()
 	pathVar := os.Getenv("PATH")
@@ -163,11 +379,13 @@ func (c context) GetShellVars(includeProcessVars bool) []string {
 		pathVar = dir + ":" + pathVar
 	}
 
-	// Collect all current process env vars, except PATH
+	// Collect all current process env vars, except PATH and secrets, which
+	// get resolved along with saved ones
 	var env []string
 	if includeProcessVars {
 		for _, entry := range os.Environ() {
-			if !strings.HasPrefix(entry, "PATH=") {
+			name := strings.SplitN(entry, "=", 2)[0]
+			if _, ok := secretValues[name]; !ok && name != "PATH" {
 				env = append(env, entry)
 			}
 		}
@@ -178,14 +396,36 @@ func (c context) GetShellVars(includeProcessVars bool) []string {
 	env = append(env, entry)
 	logging.Log(entry)
 
-	// Then values env vars
-	logging.Log("Environment variables:")
-	for key, value := range c.project.Vars {
-		entry := fmt.Sprintf("%s=%v", key, value)
+	// Then active profile, if any
+	if c.project.Profile != "" {
+		entry := fmt.Sprintf("%s=%s", profileVar, c.project.Profile)
 		env = append(env, entry)
 		logging.Log(entry)
 	}
-	return env
+
+	// Then values env vars, including computed ones, with lists and maps
+	// flattened into multiple env vars
+	logging.Log("Environment variables:")
+	for _, vars := range []map[string]interface{}{c.getVarsWithDefaults(), computed} {
+		for key, value := range vars {
+			entries, err := conversion.ToEnvVars(key, value)
+			if err != nil {
+				logging.Warning("%v", err)
+				continue
+			}
+			for _, entry := range entries {
+				env = append(env, entry)
+				logging.Log(entry)
+			}
+		}
+	}
+
+	// Finally secret env vars, with redacted values in logs
+	for key, value := range secretValues {
+		env = append(env, fmt.Sprintf("%s=%s", key, value))
+		logging.Log("%s=%s", key, secrets.Redacted)
+	}
+	return env, nil
 }
 
 // GetAction returns action with given name within same
@@ -217,3 +457,71 @@ func (c context) GetTemplateDir() string {
 func (c context) GetProjectDir() string {
 	return c.project.Dir
 }
+
+// GetProjectFileSystem returns the file system through which templates read
+// project files, which is the OS one, unless overridden while rendering.
+func (c context) GetProjectFileSystem() vfs.Reader {
+	return vfs.OS{}
+}
+
+// IsDryRun returns whether executables should only report what they would do,
+// without actually modifying anything on disk or executing any shell command.
+func (c context) IsDryRun() bool {
+	return c.dryRun
+}
+
+// GetPartials returns the shared template files whose {{define}} blocks are
+// available to all templates, file names and prompts.
+func (c context) GetPartials() []evaluation.Partial {
+	return c.partials
+}
+
+// IsStrict returns whether references to undefined variables should fail
+// evaluation, either because of the --strict flag or the template's spec.
+func (c context) IsStrict() bool {
+	return c.strict || c.spec.Strict
+}
+
+// GetDelimiters returns the delimiters of template actions in rendered files
+// and their names, as specified in template's spec.
+func (c context) GetDelimiters() evaluation.Delimiters {
+	return c.spec.Delimiters
+}
+
+// GetOverwritePolicy returns the overwrite policy forced via command line for
+// all render steps, or evaluation.DefaultOverwrite to let steps decide.
+func (c context) GetOverwritePolicy() evaluation.OverwritePolicy {
+	return c.overwrite
+}
+
+// NewRenderContext creates a context for rendering the given template dir (typically another
+// revision of current project's template) with current project's variables. Because it is meant
+// for rendering into scratch dirs, it never operates in dry-run mode.
+func NewRenderContext(execContext exec.Context, templateDir string) (evaluation.Context, error) {
+	c, ok := execContext.(context)
+	if !ok {
+		return nil, fmt.Errorf("unsupported context type %T", execContext)
+	}
+
+	specification, err := spec.Load(templateDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Locate root of templates repo relative to given template dir, the same way as for current one
+	rootDir, err := filepath.Rel(c.templateDir, c.cloneSubDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate templates repo root relative to %q: %w", c.templateDir, err)
+	}
+	partials, err := evaluation.LoadPartials(filepath.Join(templateDir, rootDir), templateDir)
+	if err != nil {
+		return nil, err
+	}
+
+	c.templateDir = templateDir
+	c.spec = *specification
+	c.partials = partials
+	c.dryRun = false
+	c.computed = &computedVars{}
+	return c, nil
+}
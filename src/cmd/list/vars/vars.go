@@ -3,8 +3,11 @@ package vars
 import (
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/Samasource/jen/src/cmd/internal"
+	"github.com/Samasource/jen/src/internal/helpers/conversion"
+	"github.com/Samasource/jen/src/internal/secrets"
 	"github.com/spf13/cobra"
 )
 
@@ -29,6 +32,22 @@ func run(options *internal.Options, args []string) error {
 
 	vars := execContext.GetVars()
 
+	// Variables missing from project file are listed with their default value
+	schema := execContext.GetVarSchema()
+	defaults := make(map[string]bool)
+	for name, v := range schema {
+		if _, ok := vars[name]; !ok && v.Default != nil {
+			vars[name] = v.Default
+			defaults[name] = true
+		}
+	}
+
+	// Secret variables are listed with redacted values
+	for name := range execContext.GetSecrets() {
+		vars[name] = secrets.Redacted
+		delete(defaults, name)
+	}
+
 	// Sort names
 	names := make([]string, 0, len(vars))
 	for name := range vars {
@@ -36,10 +55,30 @@ func run(options *internal.Options, args []string) error {
 	}
 	sort.Strings(names)
 
-	// Print names and values
+	// Print names and values, with lists and maps in json format, followed by
+	// descriptions declared in schema and whether they are defaults or come from
+	// active profile
+	profile := execContext.GetProfile()
 	for _, name := range names {
-		value := vars[name]
-		fmt.Printf("%s: %v\n", name, value)
+		value, err := conversion.ToString(vars[name])
+		if err != nil {
+			value = fmt.Sprintf("%v", vars[name])
+		}
+		var comments []string
+		if description := schema[name].Description; description != "" {
+			comments = append(comments, description)
+		}
+		if defaults[name] {
+			comments = append(comments, "default")
+		}
+		if profile != "" && execContext.IsProfileVar(name) {
+			comments = append(comments, fmt.Sprintf("from profile %q", profile))
+		}
+		if len(comments) > 0 {
+			fmt.Printf("%s: %s  # %s\n", name, value, strings.Join(comments, "; "))
+			continue
+		}
+		fmt.Printf("%s: %s\n", name, value)
 	}
 	return nil
 }
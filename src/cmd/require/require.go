@@ -5,6 +5,9 @@ import (
 	"os"
 
 	"github.com/Samasource/jen/src/cmd/internal"
+	"github.com/Samasource/jen/src/internal/project"
+	"github.com/Samasource/jen/src/internal/schema"
+	"github.com/Samasource/jen/src/internal/spec"
 	"github.com/spf13/cobra"
 )
 
@@ -12,7 +15,7 @@ import (
 func New(options *internal.Options) *cobra.Command {
 	return &cobra.Command{
 		Use:   "require",
-		Short: "Validates that all given variables are defined in current environment",
+		Short: "Validates that all given variables (or all required ones declared in template spec) are defined in current environment and conform to their declaration",
 		RunE: func(_ *cobra.Command, args []string) error {
 			return run(options, args)
 		},
@@ -20,12 +23,31 @@ func New(options *internal.Options) *cobra.Command {
 }
 
 func run(options *internal.Options, args []string) error {
+	vars, err := loadVarSchema()
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		for _, name := range vars.Names() {
+			if vars[name].Required {
+				args = append(args, name)
+			}
+		}
+	}
+
 	valid := true
 	for _, arg := range args {
-		_, ok := os.LookupEnv(arg)
+		value, ok := os.LookupEnv(arg)
 		if !ok {
 			fmt.Fprintf(os.Stderr, "Missing required variable %q\n", arg)
 			valid = false
+			continue
+		}
+		if v, ok := vars[arg]; ok {
+			if _, err := v.Parse(value); err != nil {
+				fmt.Fprintf(os.Stderr, "Invalid variable %q: %v\n", arg, err)
+				valid = false
+			}
 		}
 	}
 
@@ -34,3 +56,25 @@ func run(options *internal.Options, args []string) error {
 	}
 	return nil
 }
+
+// loadVarSchema returns the variables declared in spec of current project's
+// template, or nil when not within a project
+func loadVarSchema() (schema.Schema, error) {
+	projectDir, err := project.GetProjectDir()
+	if err != nil || projectDir == "" {
+		return nil, err
+	}
+	proj, err := project.Load(projectDir)
+	if err != nil {
+		return nil, err
+	}
+	templateDir, err := proj.GetTemplateDir()
+	if err != nil {
+		return nil, err
+	}
+	specification, err := spec.Load(templateDir)
+	if err != nil {
+		return nil, err
+	}
+	return specification.Vars, nil
+}
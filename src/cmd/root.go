@@ -9,6 +9,7 @@ import (
 	"github.com/Samasource/jen/src/cmd/pull"
 	"github.com/Samasource/jen/src/cmd/require"
 	"github.com/Samasource/jen/src/cmd/shell"
+	"github.com/Samasource/jen/src/cmd/upgrade"
 	"github.com/Samasource/jen/src/cmd/versioning"
 	"github.com/Samasource/jen/src/internal/logging"
 	"github.com/spf13/cobra"
@@ -30,6 +31,9 @@ continues to support you throughout development in executing project-related com
 	c.PersistentFlags().StringVarP(&options.TemplateName, "template", "t", "", "Name of template to use (defaults to prompting user)")
 	c.PersistentFlags().BoolVarP(&options.SkipConfirm, "yes", "y", false, "skip all confirmation prompts")
 	c.PersistentFlags().StringSliceVarP(&options.VarOverrides, "set", "s", []string{}, "sets a project variable manually (can be used multiple times)")
+	c.PersistentFlags().StringVarP(&options.Profile, "profile", "p", "", "name of profile whose variables overlay base ones (defaults to JEN_PROFILE env var)")
+	c.PersistentFlags().BoolVar(&options.CreateProfile, "create-profile", false, "create active profile if it does not exist yet")
+	c.PersistentFlags().BoolVar(&options.Strict, "strict", false, "fail on references to undefined variables in templates and expressions")
 	c.AddCommand(versioning.New(version))
 	c.AddCommand(pull.New())
 	c.AddCommand(do.New(&options))
@@ -38,5 +42,6 @@ continues to support you throughout development in executing project-related com
 	c.AddCommand(list.New(&options))
 	c.AddCommand(export.New(&options))
 	c.AddCommand(require.New(&options))
+	c.AddCommand(upgrade.New(&options))
 	return c
 }
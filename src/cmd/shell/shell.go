@@ -24,5 +24,9 @@ func run(options *internal.Options, args []string) error {
 		return err
 	}
 
-	return shell.Execute(execContext.GetShellVars(true), "", "$SHELL")
+	env, err := execContext.GetShellVars(true)
+	if err != nil {
+		return err
+	}
+	return shell.Execute(env, "", "$SHELL")
 }
@@ -0,0 +1,34 @@
+package upgrade
+
+import (
+	"github.com/Samasource/jen/src/cmd/internal"
+	"github.com/Samasource/jen/src/internal/evaluation"
+	"github.com/Samasource/jen/src/internal/upgrade"
+	"github.com/spf13/cobra"
+)
+
+// New creates a cobra command
+func New(options *internal.Options) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Re-applies current version of template to project, merging template changes with local modifications",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, args []string) error {
+			return run(options, args)
+		},
+	}
+	c.Flags().BoolVar(&options.DryRun, "dry-run", false, "only report files that would be modified, without modifying anything")
+	return c
+}
+
+func run(options *internal.Options, args []string) error {
+	execContext, err := options.NewContext()
+	if err != nil {
+		return err
+	}
+
+	newContext := func(templateDir string) (evaluation.Context, error) {
+		return internal.NewRenderContext(execContext, templateDir)
+	}
+	return upgrade.Upgrade(execContext.GetProjectDir(), execContext.GetTemplateDir(), newContext, execContext.IsDryRun())
+}
@@ -0,0 +1,158 @@
+package casing
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Style represents a way of formatting a sequence of words as a single identifier
+type Style string
+
+const (
+	// Lower lowercases all letters, preserving separators (ie: "my-project")
+	Lower Style = "lower"
+
+	// Upper uppercases all letters, preserving separators (ie: "MY-PROJECT")
+	Upper Style = "upper"
+
+	// Title capitalizes each word, preserving separators (ie: "My-Project")
+	Title Style = "title"
+
+	// Camel joins words, capitalizing all but first one (ie: "myProject")
+	Camel Style = "camel"
+
+	// Pascal joins words, capitalizing all of them (ie: "MyProject")
+	Pascal Style = "pascal"
+
+	// Snake joins lowercase words with underscores (ie: "my_project")
+	Snake Style = "snake"
+
+	// ScreamingSnake joins uppercase words with underscores (ie: "MY_PROJECT")
+	ScreamingSnake Style = "screaming-snake"
+
+	// Kebab joins lowercase words with dashes (ie: "my-project")
+	Kebab Style = "kebab"
+
+	// Dotted joins lowercase words with dots (ie: "my.project")
+	Dotted Style = "dotted"
+)
+
+// Styles lists all supported styles, in order of precedence when different styles
+// produce the same output
+var Styles = []Style{Lower, Upper, Title, Camel, Pascal, Snake, ScreamingSnake, Kebab, Dotted}
+
+// Split splits given text into words, at any non-alphanumeric character and at case
+// transitions (ie: "myHTTPServer" yields "my", "HTTP" and "Server")
+func Split(text string) []string {
+	var words []string
+	runes := []rune(text)
+	start := -1
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if start != -1 {
+				words = append(words, string(runes[start:i]))
+				start = -1
+			}
+			continue
+		}
+		if start != -1 && unicode.IsUpper(r) {
+			previous := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextIsLower) {
+				words = append(words, string(runes[start:i]))
+				start = i
+			}
+		}
+		if start == -1 {
+			start = i
+		}
+	}
+	if start != -1 {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+// Format formats given text according to given style
+func Format(text string, style Style) (string, error) {
+	switch style {
+	case Lower:
+		return strings.ToLower(text), nil
+	case Upper:
+		return strings.ToUpper(text), nil
+	case Title:
+		return title(text), nil
+	}
+
+	words := Split(text)
+	switch style {
+	case Camel:
+		for i, word := range words {
+			if i == 0 {
+				words[i] = strings.ToLower(word)
+			} else {
+				words[i] = capitalize(word)
+			}
+		}
+		return strings.Join(words, ""), nil
+	case Pascal:
+		for i, word := range words {
+			words[i] = capitalize(word)
+		}
+		return strings.Join(words, ""), nil
+	case Snake:
+		return strings.ToLower(strings.Join(words, "_")), nil
+	case ScreamingSnake:
+		return strings.ToUpper(strings.Join(words, "_")), nil
+	case Kebab:
+		return strings.ToLower(strings.Join(words, "-")), nil
+	case Dotted:
+		return strings.ToLower(strings.Join(words, ".")), nil
+	default:
+		return "", fmt.Errorf("unsupported case style %q", style)
+	}
+}
+
+// Detect determines the style in which given text is formatted. Multi-word texts are first checked
+// against styles joining words, while single words are checked against lower, upper and title styles.
+func Detect(text string) (Style, error) {
+	candidates := Styles
+	if len(Split(text)) > 1 {
+		candidates = append(append([]Style{}, Styles[3:]...), Styles[:3]...)
+	}
+	for _, style := range candidates {
+		if formatted, _ := Format(text, style); formatted == text {
+			return style, nil
+		}
+	}
+	return "", fmt.Errorf("cannot detect case style of %q", text)
+}
+
+// title capitalizes the first letter of each word, lowercasing the rest, while preserving separators
+func title(text string) string {
+	runes := []rune(text)
+	inWord := false
+	for i, r := range runes {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if inWord {
+				runes[i] = unicode.ToLower(r)
+			} else {
+				runes[i] = unicode.ToUpper(r)
+			}
+			inWord = true
+		} else {
+			inWord = false
+		}
+	}
+	return string(runes)
+}
+
+// capitalize uppercases the first letter of given word and lowercases the rest
+func capitalize(word string) string {
+	runes := []rune(strings.ToLower(word))
+	if len(runes) > 0 {
+		runes[0] = unicode.ToUpper(runes[0])
+	}
+	return string(runes)
+}
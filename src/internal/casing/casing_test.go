@@ -0,0 +1,85 @@
+package casing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplit(t *testing.T) {
+	fixtures := []struct {
+		Text     string
+		Expected []string
+	}{
+		{Text: "project", Expected: []string{"project"}},
+		{Text: "my-project", Expected: []string{"my", "project"}},
+		{Text: "my_cool project.name", Expected: []string{"my", "cool", "project", "name"}},
+		{Text: "myProject", Expected: []string{"my", "Project"}},
+		{Text: "MyHTTPServer", Expected: []string{"My", "HTTP", "Server"}},
+		{Text: "MY_PROJECT", Expected: []string{"MY", "PROJECT"}},
+		{Text: "api2Gateway", Expected: []string{"api2", "Gateway"}},
+		{Text: "--", Expected: nil},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.Text, func(t *testing.T) {
+			assert.Equal(t, f.Expected, Split(f.Text))
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	expected := map[Style]string{
+		Lower:          "my-cool project",
+		Upper:          "MY-COOL PROJECT",
+		Title:          "My-Cool Project",
+		Camel:          "myCoolProject",
+		Pascal:         "MyCoolProject",
+		Snake:          "my_cool_project",
+		ScreamingSnake: "MY_COOL_PROJECT",
+		Kebab:          "my-cool-project",
+		Dotted:         "my.cool.project",
+	}
+
+	for _, style := range Styles {
+		t.Run(string(style), func(t *testing.T) {
+			actual, err := Format("my-Cool project", style)
+			assert.NoError(t, err)
+			assert.Equal(t, expected[style], actual)
+		})
+	}
+
+	_, err := Format("text", Style("unknown"))
+	assert.EqualError(t, err, `unsupported case style "unknown"`)
+}
+
+func TestDetect(t *testing.T) {
+	fixtures := []struct {
+		Text     string
+		Expected Style
+		Error    string
+	}{
+		{Text: "projekt", Expected: Lower},
+		{Text: "PROJEKT", Expected: Upper},
+		{Text: "Projekt", Expected: Title},
+		{Text: "myProjekt", Expected: Camel},
+		{Text: "MyProjekt", Expected: Pascal},
+		{Text: "my_projekt", Expected: Snake},
+		{Text: "MY_PROJEKT", Expected: ScreamingSnake},
+		{Text: "my-projekt", Expected: Kebab},
+		{Text: "my.projekt", Expected: Dotted},
+		{Text: "My projekt", Error: `cannot detect case style of "My projekt"`},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.Text, func(t *testing.T) {
+			actual, err := Detect(f.Text)
+			if f.Error != "" {
+				assert.EqualError(t, err, f.Error)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, f.Expected, actual)
+			}
+		})
+	}
+}
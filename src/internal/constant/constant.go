@@ -1,10 +1,16 @@
 package constant
 
 const (
-	SpecFileName       = "spec.yaml"
-	DefaultCloneDir    = ".jen/repo"
-	TemplatesDirName   = "templates"
-	ProjectFileName    = "jen.yaml"
-	SpecFileVersion    = "0.2.0"
-	ProjectFileVersion = "0.2.0"
+	SpecFileName        = "spec.yaml"
+	DefaultCloneDir     = ".jen/repo"
+	DefaultSecretsFile  = ".jen/secrets.yaml"
+	TemplatesDirName    = "templates"
+	ProjectFileName     = "jen.yaml"
+	SpecFileVersion     = "0.2.0"
+	ProjectFileVersion  = "0.2.0"
+	ManifestFileName    = "jen.manifest.yaml"
+	ManifestFileVersion = "0.2.0"
+	IgnoreFileName      = ".jenignore"
+	PartialsDirName     = "_partials"
+	DelimitersFileName  = ".jendelims"
 )
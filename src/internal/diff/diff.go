@@ -0,0 +1,185 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines displayed around each change
+const contextLines = 3
+
+// OpKind identifies whether a line was kept, removed or added
+type OpKind int
+
+const (
+	// Equal indicates a line present in both texts
+	Equal OpKind = iota
+
+	// Delete indicates a line only present in old text
+	Delete
+
+	// Insert indicates a line only present in new text
+	Insert
+)
+
+// Op represents a single line operation required to transform old text into new text
+type Op struct {
+	Kind OpKind
+	Line string
+}
+
+// SplitLines splits given text into lines, preserving line terminators, so that
+// joining them back yields the exact original text
+func SplitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Lines computes the minimal sequence of line operations transforming oldLines into newLines,
+// based on their longest common subsequence
+func Lines(oldLines, newLines []string) []Op {
+	// Compute LCS lengths of all suffixes
+	lengths := make([][]int, len(oldLines)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(newLines)+1)
+	}
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	// Walk LCS table to produce operations
+	var ops []Op
+	i, j := 0, 0
+	for i < len(oldLines) && j < len(newLines) {
+		if oldLines[i] == newLines[j] {
+			ops = append(ops, Op{Kind: Equal, Line: oldLines[i]})
+			i++
+			j++
+		} else if lengths[i+1][j] >= lengths[i][j+1] {
+			ops = append(ops, Op{Kind: Delete, Line: oldLines[i]})
+			i++
+		} else {
+			ops = append(ops, Op{Kind: Insert, Line: newLines[j]})
+			j++
+		}
+	}
+	for ; i < len(oldLines); i++ {
+		ops = append(ops, Op{Kind: Delete, Line: oldLines[i]})
+	}
+	for ; j < len(newLines); j++ {
+		ops = append(ops, Op{Kind: Insert, Line: newLines[j]})
+	}
+	return ops
+}
+
+// Unified returns a unified diff between old and new texts, labelled with given names,
+// or an empty string if both texts are identical
+func Unified(oldName, newName, oldText, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+	ops := Lines(SplitLines(oldText), SplitLines(newText))
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "--- %s\n+++ %s\n", oldName, newName)
+
+	// Group operations into hunks surrounded by a few context lines
+	for start := 0; start < len(ops); {
+		// Find next change
+		first := start
+		for first < len(ops) && ops[first].Kind == Equal {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+
+		// Extend hunk until we reach enough unchanged lines to separate it from the next change
+		last := first
+		for k := first; k < len(ops); k++ {
+			if ops[k].Kind != Equal {
+				last = k
+			} else if k-last > 2*contextLines {
+				break
+			}
+		}
+
+		hunkStart := max(first-contextLines, start)
+		hunkEnd := min(last+contextLines+1, len(ops))
+		writeHunk(&builder, ops, hunkStart, hunkEnd)
+		start = hunkEnd
+	}
+	return builder.String()
+}
+
+// writeHunk writes operations between given indices as a single unified diff hunk
+func writeHunk(builder *strings.Builder, ops []Op, start, end int) {
+	// Determine hunk's starting line numbers and lengths in both texts
+	oldLine, newLine := 1, 1
+	for _, op := range ops[:start] {
+		if op.Kind != Insert {
+			oldLine++
+		}
+		if op.Kind != Delete {
+			newLine++
+		}
+	}
+	oldCount, newCount := 0, 0
+	for _, op := range ops[start:end] {
+		if op.Kind != Insert {
+			oldCount++
+		}
+		if op.Kind != Delete {
+			newCount++
+		}
+	}
+	// By convention, an empty range refers to the line preceding it
+	if oldCount == 0 {
+		oldLine--
+	}
+	if newCount == 0 {
+		newLine--
+	}
+	fmt.Fprintf(builder, "@@ -%d,%d +%d,%d @@\n", oldLine, oldCount, newLine, newCount)
+
+	for _, op := range ops[start:end] {
+		prefix := " "
+		if op.Kind == Delete {
+			prefix = "-"
+		} else if op.Kind == Insert {
+			prefix = "+"
+		}
+		builder.WriteString(prefix + op.Line)
+		if !strings.HasSuffix(op.Line, "\n") {
+			builder.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
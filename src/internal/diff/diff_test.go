@@ -0,0 +1,86 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnified(t *testing.T) {
+	fixtures := []struct {
+		Name     string
+		Old      string
+		New      string
+		Expected string
+	}{
+		{
+			Name:     "identical",
+			Old:      "line 1\nline 2\n",
+			New:      "line 1\nline 2\n",
+			Expected: "",
+		},
+		{
+			Name: "changed line",
+			Old:  "line 1\nline 2\nline 3\n",
+			New:  "line 1\nline two\nline 3\n",
+			Expected: `--- old
++++ new
+@@ -1,3 +1,3 @@
+ line 1
+-line 2
++line two
+ line 3
+`,
+		},
+		{
+			Name: "added lines to empty text",
+			Old:  "",
+			New:  "line 1\nline 2\n",
+			Expected: `--- old
++++ new
+@@ -0,0 +1,2 @@
++line 1
++line 2
+`,
+		},
+		{
+			Name: "missing final newline",
+			Old:  "line 1\n",
+			New:  "line 1\nline 2",
+			Expected: `--- old
++++ new
+@@ -1,1 +1,2 @@
+ line 1
++line 2
+\ No newline at end of file
+`,
+		},
+		{
+			Name: "distant changes produce separate hunks",
+			Old:  "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\n",
+			New:  "A\nb\nc\nd\ne\nf\ng\nh\ni\nj\nK\n",
+			Expected: `--- old
++++ new
+@@ -1,4 +1,4 @@
+-a
++A
+ b
+ c
+ d
+@@ -8,4 +8,4 @@
+ h
+ i
+ j
+-k
++K
+`,
+		},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			actual := Unified("old", "new", f.Old, f.New)
+			assert.Equal(t, f.Expected, actual)
+		})
+	}
+}
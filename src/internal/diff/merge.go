@@ -0,0 +1,118 @@
+package diff
+
+import "strings"
+
+// Labels of conflict markers inserted by Merge3
+const (
+	conflictStart     = "<<<<<<< current\n"
+	conflictSeparator = "=======\n"
+	conflictEnd       = ">>>>>>> template\n"
+)
+
+// Merge3 performs a line-based three-way merge of changes made from base text to both current and
+// other texts. Non-overlapping changes are combined, while overlapping ones are surrounded by conflict
+// markers. It returns the merged text and whether any conflicts were encountered.
+func Merge3(base, current, other string) (string, bool) {
+	baseLines := SplitLines(base)
+	currentLines := SplitLines(current)
+	otherLines := SplitLines(other)
+	currentMatches := matchLines(baseLines, currentLines)
+	otherMatches := matchLines(baseLines, otherLines)
+
+	var builder strings.Builder
+	conflicts := false
+	i, c, o := 0, 0, 0
+	for {
+		// Output lines that are stable in all three texts
+		for i < len(baseLines) && currentMatches[i] == c && otherMatches[i] == o {
+			builder.WriteString(baseLines[i])
+			i++
+			c++
+			o++
+		}
+
+		// Find next base line that is stable in all three texts
+		next := i
+		for next < len(baseLines) && (currentMatches[next] == -1 || otherMatches[next] == -1) {
+			next++
+		}
+		nextCurrent, nextOther := len(currentLines), len(otherLines)
+		if next < len(baseLines) {
+			nextCurrent, nextOther = currentMatches[next], otherMatches[next]
+		}
+
+		// Resolve unstable chunk in-between
+		baseChunk := baseLines[i:next]
+		currentChunk := currentLines[c:nextCurrent]
+		otherChunk := otherLines[o:nextOther]
+		switch {
+		case equalLines(currentChunk, baseChunk):
+			writeLines(&builder, otherChunk)
+		case equalLines(otherChunk, baseChunk), equalLines(currentChunk, otherChunk):
+			writeLines(&builder, currentChunk)
+		default:
+			conflicts = true
+			builder.WriteString(conflictStart)
+			writeLines(&builder, terminateLastLine(currentChunk))
+			builder.WriteString(conflictSeparator)
+			writeLines(&builder, terminateLastLine(otherChunk))
+			builder.WriteString(conflictEnd)
+		}
+
+		if next >= len(baseLines) {
+			break
+		}
+		i, c, o = next, nextCurrent, nextOther
+	}
+	return builder.String(), conflicts
+}
+
+// matchLines returns, for each line of base, the index of the matching line in other, or -1 if
+// that line was deleted
+func matchLines(base, other []string) []int {
+	matches := make([]int, len(base))
+	i, j := 0, 0
+	for _, op := range Lines(base, other) {
+		switch op.Kind {
+		case Equal:
+			matches[i] = j
+			i++
+			j++
+		case Delete:
+			matches[i] = -1
+			i++
+		case Insert:
+			j++
+		}
+	}
+	return matches
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func writeLines(builder *strings.Builder, lines []string) {
+	for _, line := range lines {
+		builder.WriteString(line)
+	}
+}
+
+// terminateLastLine ensures last line ends with a line terminator, so that conflict markers
+// always start on their own line
+func terminateLastLine(lines []string) []string {
+	if len(lines) == 0 || strings.HasSuffix(lines[len(lines)-1], "\n") {
+		return lines
+	}
+	result := append([]string(nil), lines...)
+	result[len(result)-1] += "\n"
+	return result
+}
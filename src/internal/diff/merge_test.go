@@ -0,0 +1,98 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge3(t *testing.T) {
+	fixtures := []struct {
+		Name              string
+		Base              string
+		Current           string
+		Other             string
+		Expected          string
+		ExpectedConflicts bool
+	}{
+		{
+			Name:     "no changes",
+			Base:     "a\nb\nc\n",
+			Current:  "a\nb\nc\n",
+			Other:    "a\nb\nc\n",
+			Expected: "a\nb\nc\n",
+		},
+		{
+			Name:     "only current changed",
+			Base:     "a\nb\nc\n",
+			Current:  "a\nB\nc\n",
+			Other:    "a\nb\nc\n",
+			Expected: "a\nB\nc\n",
+		},
+		{
+			Name:     "only other changed",
+			Base:     "a\nb\nc\n",
+			Current:  "a\nb\nc\n",
+			Other:    "a\nb\nC\nd\n",
+			Expected: "a\nb\nC\nd\n",
+		},
+		{
+			Name:     "non-overlapping changes",
+			Base:     "a\nb\nc\nd\ne\n",
+			Current:  "A\nb\nc\nd\ne\n",
+			Other:    "a\nb\nc\nd\nE\n",
+			Expected: "A\nb\nc\nd\nE\n",
+		},
+		{
+			Name:     "identical changes",
+			Base:     "a\nb\nc\n",
+			Current:  "a\nx\nc\n",
+			Other:    "a\nx\nc\n",
+			Expected: "a\nx\nc\n",
+		},
+		{
+			Name:     "deletion and insertion elsewhere",
+			Base:     "a\nb\nc\nd\n",
+			Current:  "a\nc\nd\n",
+			Other:    "a\nb\nc\nd\ne\n",
+			Expected: "a\nc\nd\ne\n",
+		},
+		{
+			Name:    "conflicting changes",
+			Base:    "a\nb\nc\n",
+			Current: "a\nmine\nc\n",
+			Other:   "a\ntheirs\nc\n",
+			Expected: `a
+<<<<<<< current
+mine
+=======
+theirs
+>>>>>>> template
+c
+`,
+			ExpectedConflicts: true,
+		},
+		{
+			Name:    "conflicting changes without final newline",
+			Base:    "a\nb",
+			Current: "a\nmine",
+			Other:   "a\ntheirs",
+			Expected: `a
+<<<<<<< current
+mine
+=======
+theirs
+>>>>>>> template
+`,
+			ExpectedConflicts: true,
+		},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			actual, conflicts := Merge3(f.Base, f.Current, f.Other)
+			assert.Equal(t, f.Expected, actual)
+			assert.Equal(t, f.ExpectedConflicts, conflicts)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package evaluation
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+
+	"github.com/Samasource/jen/src/internal/vfs"
+)
+
+// sniffLength is the number of leading bytes inspected to determine whether a file is binary, which is also the
+// maximum length of front matter
+const sniffLength = 8000
+
+// isBinaryFile determines whether given file must be copied byte-for-byte, either because its path matches one of
+// given glob patterns or because its content looks binary
+func isBinaryFile(fsys vfs.Reader, path string, patterns []string) (bool, error) {
+	if matchBinaryPattern(path, patterns) {
+		return true, nil
+	}
+	return HasBinaryContent(fsys, path)
+}
+
+// HasBinaryContent determines whether content of given file looks binary, only reading its first few KBs
+func HasBinaryContent(fsys vfs.Reader, path string) (bool, error) {
+	prefix, err := readPrefix(fsys, path)
+	if err != nil {
+		return false, err
+	}
+	return IsBinaryContent(prefix), nil
+}
+
+// matchBinaryPattern determines whether given path matches any of given glob patterns of binary files
+func matchBinaryPattern(path string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matchPattern(path, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// readPrefix returns the first few KBs of given file, which are enough to determine whether it is binary
+// and to find its front matter, without loading it fully into memory
+func readPrefix(fsys vfs.Reader, path string) ([]byte, error) {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %q: %w", path, err)
+	}
+	defer file.Close()
+
+	buffer := make([]byte, sniffLength)
+	n, err := io.ReadFull(file, buffer)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("failed to read file %q: %w", path, err)
+	}
+	return buffer[:n], nil
+}
+
+// IsBinaryContent determines whether given content looks binary, using the same heuristic as git,
+// that is whether it contains a NUL byte within its first few KBs
+func IsBinaryContent(content []byte) bool {
+	if len(content) > sniffLength {
+		content = content[:sniffLength]
+	}
+	return bytes.IndexByte(content, 0) != -1
+}
+
+// HashFile returns the SHA-256 hex digest of given file's content, without loading it fully into memory
+func HashFile(fsys vfs.Reader, path string) (string, error) {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
@@ -0,0 +1,108 @@
+package evaluation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// ComputedVars maps names of variables derived from other variables to the template expressions determining
+// their values (ie: "{{ .PROJECT | lower }}"). Those variables are evaluated on demand and never saved to
+// project file.
+type ComputedVars map[string]string
+
+// Order returns the names of computed variables in the order they must be evaluated, that is with variables
+// always following those they depend on, or an error if some expressions are invalid or dependencies form a cycle
+func (c ComputedVars) Order() ([]string, error) {
+	deps := make(map[string][]string, len(c))
+	for _, name := range c.names() {
+		refs, err := findVarRefs(c[name])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse computed variable %q: %w", name, err)
+		}
+		for _, ref := range refs {
+			if _, ok := c[ref]; ok {
+				deps[name] = append(deps[name], ref)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	states := make(map[string]int, len(c))
+	order := make([]string, 0, len(c))
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		path = append(path, name)
+		switch states[name] {
+		case visiting:
+			return fmt.Errorf("computed variables have a circular dependency: %s", strings.Join(path, " -> "))
+		case visited:
+			return nil
+		}
+		states[name] = visiting
+		for _, dep := range deps[name] {
+			if err := visit(dep, path); err != nil {
+				return err
+			}
+		}
+		states[name] = visited
+		order = append(order, name)
+		return nil
+	}
+	for _, name := range c.names() {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// Eval evaluates all computed variables in dependency order, using given context, whose own evaluation variables
+// must not include computed ones, and returns their values
+func (c ComputedVars) Eval(context Context) (map[string]interface{}, error) {
+	order, err := c.Order()
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]interface{}, len(c))
+	for _, name := range order {
+		value, err := EvalTemplate(WithVars(context, values), c[name])
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate computed variable %q: %w", name, err)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// names returns the sorted names of computed variables, for deterministic ordering
+func (c ComputedVars) names() []string {
+	names := make([]string, 0, len(c))
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// findVarRefs returns the names of root variables referenced by given template text
+func findVarRefs(text string) ([]string, error) {
+	tmpl := template.New("computed")
+	tmpl, err := tmpl.Funcs(newFuncMap(nil, tmpl)).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	var refs []string
+	walker := varRefsWalker{
+		visit: func(name string, offset int) {
+			refs = append(refs, name)
+		},
+	}
+	walker.walk(tmpl.Tree.Root, true)
+	return refs, nil
+}
@@ -0,0 +1,102 @@
+package evaluation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputedVarsOrder(t *testing.T) {
+	fixtures := []struct {
+		Name     string
+		Computed ComputedVars
+		Expected []string
+		Error    string
+	}{
+		{
+			Name: "independent",
+			Computed: ComputedVars{
+				"B": "{{ .PROJECT | upper }}",
+				"A": "{{ .PROJECT | lower }}",
+			},
+			Expected: []string{"A", "B"},
+		},
+		{
+			Name: "dependencies",
+			Computed: ComputedVars{
+				"A": `{{ .B }}-{{ $.C }}`,
+				"B": `{{ .C | replace "_" "-" }}`,
+				"C": `{{ .PROJECT | lower }}`,
+			},
+			Expected: []string{"C", "B", "A"},
+		},
+		{
+			Name: "dependency within condition",
+			Computed: ComputedVars{
+				"A": `{{ if .B }}yes{{ end }}`,
+				"B": `{{ .PSQL }}`,
+			},
+			Expected: []string{"B", "A"},
+		},
+		{
+			Name: "cycle",
+			Computed: ComputedVars{
+				"A": `{{ .B }}`,
+				"B": `{{ .C }}`,
+				"C": `{{ .A }}`,
+			},
+			Error: "computed variables have a circular dependency: A -> B -> C -> A",
+		},
+		{
+			Name: "self reference",
+			Computed: ComputedVars{
+				"A": `{{ .A }}`,
+			},
+			Error: "computed variables have a circular dependency: A -> A",
+		},
+		{
+			Name: "invalid expression",
+			Computed: ComputedVars{
+				"A": `{{ .B `,
+			},
+			Error: `failed to parse computed variable "A": template: computed:1: unclosed action`,
+		},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			actual, err := f.Computed.Order()
+
+			if f.Error != "" {
+				assert.EqualError(t, err, f.Error)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, f.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestComputedVarsEval(t *testing.T) {
+	context := context{
+		vars: varMap{
+			"PROJECT": "My_Project",
+		},
+	}
+	computed := ComputedVars{
+		"SLUG":  `{{ .PROJECT | lower | replace "_" "-" }}`,
+		"IMAGE": `registry/{{ .SLUG }}:latest`,
+	}
+
+	actual, err := computed.Eval(context)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"SLUG":  "my-project",
+		"IMAGE": "registry/my-project:latest",
+	}, actual)
+
+	computed["BROKEN"] = `{{ fail "oops" }}`
+	_, err = computed.Eval(context)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `failed to evaluate computed variable "BROKEN"`)
+}
@@ -0,0 +1,87 @@
+package evaluation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Samasource/jen/src/internal/vfs"
+)
+
+// Delimiters are the left and right delimiters of go template actions, where the zero value
+// stands for the default double-braces
+type Delimiters struct {
+	Left  string
+	Right string
+}
+
+const (
+	defaultLeftDelimiter  = "{{"
+	defaultRightDelimiter = "}}"
+)
+
+// ParseDelimiters parses left and right delimiters separated by whitespace (ie: "[% %]")
+func ParseDelimiters(text string) (Delimiters, error) {
+	fields := strings.Fields(text)
+	if len(fields) != 2 {
+		return Delimiters{}, fmt.Errorf("invalid delimiters %q (expected left and right delimiters separated by a space, ie: %q)", text, "[% %]")
+	}
+	delims := Delimiters{Left: fields[0], Right: fields[1]}
+	if delims.IsDefault() {
+		return Delimiters{}, nil
+	}
+	return delims, nil
+}
+
+// IsDefault determines whether delimiters are the default double-braces
+func (d Delimiters) IsDefault() bool {
+	return (d.Left == "" || d.Left == defaultLeftDelimiter) && (d.Right == "" || d.Right == defaultRightDelimiter)
+}
+
+func (d Delimiters) String() string {
+	if d.IsDefault() {
+		return defaultLeftDelimiter + " " + defaultRightDelimiter
+	}
+	return d.Left + " " + d.Right
+}
+
+// translate converts given text written with default delimiters, such as placeholder values, to these delimiters
+func (d Delimiters) translate(text string) string {
+	if d.IsDefault() {
+		return text
+	}
+	text = strings.ReplaceAll(text, defaultLeftDelimiter, d.Left)
+	return strings.ReplaceAll(text, defaultRightDelimiter, d.Right)
+}
+
+// loadDelimitersFile loads the delimiters specified in given file, returning ok=false if file does not exist
+func loadDelimitersFile(fsys vfs.Reader, path string) (Delimiters, bool, error) {
+	if !vfs.Exists(fsys, path) {
+		return Delimiters{}, false, nil
+	}
+	buf, err := fsys.ReadFile(path)
+	if err != nil {
+		return Delimiters{}, false, fmt.Errorf("failed to read delimiters file %q: %w", path, err)
+	}
+	delims, err := ParseDelimiters(string(buf))
+	if err != nil {
+		return Delimiters{}, false, fmt.Errorf("failed to parse delimiters file %q: %w", path, err)
+	}
+	return delims, true, nil
+}
+
+var delimitersDirectiveRegexp = regexp.MustCompile(`^.*\bjen:delimiters[ \t]+(\S+)[ \t]+(\S+).*(?:\r?\n|$)`)
+
+// parseDelimitersDirective looks for a directive such as "# jen:delimiters [% %]" on first line of given template
+// text and, if found, returns the delimiters it specifies and the text stripped of that line
+func parseDelimitersDirective(text string) (Delimiters, string, bool, error) {
+	match := delimitersDirectiveRegexp.FindStringSubmatchIndex(text)
+	if match == nil {
+		return Delimiters{}, text, false, nil
+	}
+	delims, err := ParseDelimiters(text[match[2]:match[3]] + " " + text[match[4]:match[5]])
+	if err != nil {
+		return Delimiters{}, "", false, err
+	}
+	return delims, text[match[1]:], true, nil
+}
@@ -0,0 +1,127 @@
+package evaluation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDelimiters(t *testing.T) {
+	fixtures := []struct {
+		Text     string
+		Expected Delimiters
+		Error    string
+	}{
+		{
+			Text:     "[% %]",
+			Expected: Delimiters{Left: "[%", Right: "%]"},
+		},
+		{
+			Text:     "  <<   >>\n",
+			Expected: Delimiters{Left: "<<", Right: ">>"},
+		},
+		{
+			Text:     "{{ }}",
+			Expected: Delimiters{},
+		},
+		{
+			Text:  "<<",
+			Error: `invalid delimiters "<<" (expected left and right delimiters separated by a space, ie: "[% %]")`,
+		},
+		{
+			Text:  "<< >> !!",
+			Error: `invalid delimiters "<< >> !!" (expected left and right delimiters separated by a space, ie: "[% %]")`,
+		},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.Text, func(t *testing.T) {
+			actual, err := ParseDelimiters(f.Text)
+
+			if f.Error != "" {
+				assert.EqualError(t, err, f.Error)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, f.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestParseDelimitersDirective(t *testing.T) {
+	fixtures := []struct {
+		Name          string
+		Text          string
+		ExpectedFound bool
+		Expected      Delimiters
+		ExpectedText  string
+	}{
+		{
+			Name:          "yaml comment",
+			Text:          "# jen:delimiters [% %]\nname: [% .NAME %]\n",
+			ExpectedFound: true,
+			Expected:      Delimiters{Left: "[%", Right: "%]"},
+			ExpectedText:  "name: [% .NAME %]\n",
+		},
+		{
+			Name:          "html comment",
+			Text:          "<!-- jen:delimiters << >> -->\r\n<p><< .NAME >></p>",
+			ExpectedFound: true,
+			Expected:      Delimiters{Left: "<<", Right: ">>"},
+			ExpectedText:  "<p><< .NAME >></p>",
+		},
+		{
+			Name:         "not on first line",
+			Text:         "name: {{ .NAME }}\n# jen:delimiters [% %]\n",
+			ExpectedText: "name: {{ .NAME }}\n# jen:delimiters [% %]\n",
+		},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			actual, text, found, err := parseDelimitersDirective(f.Text)
+
+			assert.NoError(t, err)
+			assert.Equal(t, f.ExpectedFound, found)
+			assert.Equal(t, f.Expected, actual)
+			assert.Equal(t, f.ExpectedText, text)
+		})
+	}
+}
+
+func TestEvalTemplateWithDelimiters(t *testing.T) {
+	context := context{
+		vars: varMap{
+			"NAME": "foo",
+		},
+		placeholders: Placeholders{
+			"projekt": {Value: "{{ .NAME | upper }}"},
+		},
+	}
+	src := source{line: 1, delims: Delimiters{Left: "[%", Right: "%]"}}
+
+	actual, err := evalTemplate(context, src, "[% .NAME %] projekt ${{ github.sha }} {{{ .Values }}}")
+	assert.NoError(t, err)
+	assert.Equal(t, "foo FOO ${{ github.sha }} {{{ .Values }}}", actual)
+
+	_, err = evalTemplate(context, src, "line 1\n[% fail \"oops\" %]")
+	assert.EqualError(t, err, "template:2:4: at <fail \"oops\">: error calling fail: oops\n"+
+		"    [% fail \"oops\" %]\n"+
+		"       ^")
+}
+
+func TestInsertWithDelimiters(t *testing.T) {
+	context := context{
+		vars: varMap{
+			"NAME": "foo",
+		},
+	}
+	src := source{path: "file.txt.insert", line: 2, delims: Delimiters{Left: "<<", Right: ">>"}}
+
+	insert, err := NewInsert(src, "<<<[marker=<< .NAME >>] ^start\nname: << .NAME >>\nimage: {{ .Values.image }}\n>>>\n")
+	assert.NoError(t, err)
+
+	actual, err := insert.Eval(context, "target.txt", "start foo\n")
+	assert.NoError(t, err)
+	assert.Equal(t, "start foo\n# jen:begin foo\nname: foo\nimage: {{ .Values.image }}\n# jen:end foo\n", actual)
+}
@@ -0,0 +1,251 @@
+package evaluation
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TemplateError reports a failure to parse or execute a template, located within the
+// original template file, before triple-brace escaping and placeholder replacement
+type TemplateError struct {
+	// Path is the template file's path, or empty for templates not originating from
+	// a file (ie: prompt messages)
+	Path string
+
+	// Line is the 1-based line number of error within file
+	Line int
+
+	// Column is the 1-based column number of error within line, or 0 if unknown
+	Column int
+
+	// Reason describes the error itself, without go template's own location prefix
+	Reason string
+
+	// Excerpt is the source line at which error occurred
+	Excerpt string
+
+	// ExcerptColumn is the 1-based column of error within excerpt, or 0 if unknown
+	ExcerptColumn int
+
+	// Err is the original go template error
+	Err error
+}
+
+func (e *TemplateError) Error() string {
+	var b strings.Builder
+	if e.Path != "" {
+		b.WriteString(e.Path)
+	} else {
+		b.WriteString("template")
+	}
+	fmt.Fprintf(&b, ":%d", e.Line)
+	if e.Column > 0 {
+		fmt.Fprintf(&b, ":%d", e.Column)
+	}
+	b.WriteString(": ")
+	b.WriteString(e.Reason)
+	if e.Excerpt != "" {
+		fmt.Fprintf(&b, "\n    %s", e.Excerpt)
+		if e.ExcerptColumn > 0 {
+			fmt.Fprintf(&b, "\n    %s^", caretIndent(e.Excerpt, e.ExcerptColumn-1))
+		}
+	}
+	return b.String()
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// chainedError decorates an error wrapping a template error with the chain of actions and steps that
+// triggered the evaluation, outermost first
+type chainedError struct {
+	err   error
+	chain []string
+}
+
+func (e *chainedError) Error() string {
+	return fmt.Sprintf("%s\n  in %s", e.err, strings.Join(e.chain, " > "))
+}
+
+func (e *chainedError) Unwrap() error {
+	return e.err
+}
+
+// AddToChain prepends given action or step description to the chain of actions and steps reported
+// by given error, if it wraps a template error, and otherwise returns error as is
+func AddToChain(err error, description string) error {
+	if chained, ok := err.(*chainedError); ok {
+		chained.chain = append([]string{description}, chained.chain...)
+		return chained
+	}
+	var templateErr *TemplateError
+	var undefinedErr *UndefinedVarsError
+	if errors.As(err, &templateErr) || errors.As(err, &undefinedErr) {
+		return &chainedError{
+			err:   err,
+			chain: []string{description},
+		}
+	}
+	return err
+}
+
+// setTemplateErrorPath sets the path of given error, if it is a template error whose path is not known yet
+func setTemplateErrorPath(err error, path string) {
+	var templateErr *TemplateError
+	if errors.As(err, &templateErr) && templateErr.Path == "" {
+		templateErr.Path = path
+	}
+}
+
+// caretIndent returns whitespace for aligning a caret under given byte offset of line, preserving tabs
+func caretIndent(line string, offset int) string {
+	if offset > len(line) {
+		offset = len(line)
+	}
+	indent := []byte(line[:offset])
+	for i, c := range indent {
+		if c != '\t' {
+			indent[i] = ' '
+		}
+	}
+	return string(indent)
+}
+
+// source locates a template text within the file it originates from, for error reporting, and determines its syntax
+type source struct {
+	// path is the template file's path, or empty if not known
+	path string
+
+	// line is the 1-based line of text's first character within file
+	line int
+
+	// column is the 0-based byte column of text's first character within its line
+	column int
+
+	// delims are the delimiters of template actions within text
+	delims Delimiters
+}
+
+// edit represents the replacement of the input range [start, end) by length bytes of output
+type edit struct {
+	start  int
+	end    int
+	length int
+}
+
+// sourceMap records the edits performed while preprocessing a template, in increasing order,
+// in order to map positions within preprocessed text back to the original text
+type sourceMap []edit
+
+// original returns the offset in original text corresponding to given offset in preprocessed text.
+// Offsets falling within replaced ranges map to the start of the original range.
+func (m sourceMap) original(offset int) int {
+	delta := 0
+	for _, e := range m {
+		outputStart := e.start + delta
+		if offset < outputStart {
+			break
+		}
+		if offset < outputStart+e.length {
+			return e.start
+		}
+		delta += e.length - (e.end - e.start)
+	}
+	return offset - delta
+}
+
+// templateSource holds what is required for locating errors of a given named template
+type templateSource struct {
+	source
+	original  string
+	processed string
+	maps      []sourceMap
+}
+
+// originalOffset maps given offset of preprocessed text back to original text, undoing
+// preprocessing steps in reverse order
+func (s templateSource) originalOffset(offset int) int {
+	for i := len(s.maps) - 1; i >= 0; i-- {
+		offset = s.maps[i].original(offset)
+	}
+	return offset
+}
+
+var goTemplateErrorRegexp = regexp.MustCompile(`(?s)^template: (.*?):(\d+)(?::(\d+))?: (?:executing "[^"]*" )?(.*)$`)
+
+// newTemplateError converts given go template error into a TemplateError located within the original
+// template file, using given sources of named templates. It returns the error as is when it cannot be
+// located.
+func newTemplateError(err error, sources map[string]templateSource) error {
+	match := goTemplateErrorRegexp.FindStringSubmatch(err.Error())
+	if match == nil {
+		return err
+	}
+	src, ok := sources[match[1]]
+	if !ok {
+		return err
+	}
+	line, _ := strconv.Atoi(match[2])
+	column := -1
+	if match[3] != "" {
+		column, _ = strconv.Atoi(match[3])
+	}
+
+	// Locate error in original text, column being unknown for parse errors
+	offset := offsetOf(src.processed, line, column)
+	return src.newError(offset, column != -1, match[4], err)
+}
+
+// newError returns an error with given reason, located at given offset of preprocessed text. Column is only
+// reported if known.
+func (s templateSource) newError(offset int, hasColumn bool, reason string, err error) *TemplateError {
+	originalOffset := s.originalOffset(offset)
+	lineStart := strings.LastIndex(s.original[:originalOffset], "\n") + 1
+	lineEnd := strings.Index(s.original[lineStart:], "\n")
+	if lineEnd == -1 {
+		lineEnd = len(s.original)
+	} else {
+		lineEnd += lineStart
+	}
+	originalLine := strings.Count(s.original[:lineStart], "\n")
+
+	templateErr := &TemplateError{
+		Path:    s.path,
+		Line:    s.line + originalLine,
+		Reason:  reason,
+		Excerpt: strings.TrimRight(s.original[lineStart:lineEnd], "\r"),
+		Err:     err,
+	}
+	if hasColumn {
+		templateErr.ExcerptColumn = originalOffset - lineStart + 1
+		templateErr.Column = templateErr.ExcerptColumn
+		if originalLine == 0 {
+			templateErr.Column += s.column
+		}
+	}
+	return templateErr
+}
+
+// offsetOf returns the byte offset of given 1-based line and 0-based column in text, or of the
+// line's start if column is negative
+func offsetOf(text string, line, column int) int {
+	offset := 0
+	for i := 1; i < line; i++ {
+		next := strings.Index(text[offset:], "\n")
+		if next == -1 {
+			break
+		}
+		offset += next + 1
+	}
+	if column > 0 {
+		offset += column
+	}
+	if offset > len(text) {
+		offset = len(text)
+	}
+	return offset
+}
@@ -0,0 +1,133 @@
+package evaluation
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateErrors(t *testing.T) {
+	context := context{
+		vars: varMap{
+			"NAME": "foo",
+		},
+		placeholders: Placeholders{
+			"projekt": {Value: "{{.NAME}}"},
+		},
+	}
+
+	fixtures := []struct {
+		Name     string
+		Source   source
+		Template string
+		Partials []Partial
+		Error    string
+	}{
+		{
+			Name:     "parse error",
+			Source:   source{path: "dir/file.txt", line: 1},
+			Template: "line 1\nline 2 {{ .NAME | nofunc }}\nline 3",
+			Error:    "dir/file.txt:2: function \"nofunc\" not defined\n    line 2 {{ .NAME | nofunc }}",
+		},
+		{
+			Name:     "execution error",
+			Source:   source{path: "dir/file.txt", line: 1},
+			Template: "line 1\nline 2 {{ fail \"boom\" }}\nline 3",
+			Error: "dir/file.txt:2:11: at <fail \"boom\">: error calling fail: boom\n" +
+				"    line 2 {{ fail \"boom\" }}\n" +
+				"              ^",
+		},
+		{
+			Name:     "column adjusted for triple braces and placeholders",
+			Source:   source{path: "dir/file.txt", line: 1},
+			Template: "{{{ projekt }}} projekt {{ fail \"boom\" }}",
+			Error: "dir/file.txt:1:28: at <fail \"boom\">: error calling fail: boom\n" +
+				"    {{{ projekt }}} projekt {{ fail \"boom\" }}\n" +
+				"                               ^",
+		},
+		{
+			Name:     "offset within file",
+			Source:   source{path: "dir/file.txt.insert", line: 5, column: 4},
+			Template: "{{ fail \"boom\" }}\n{{ .NAME }}",
+			Error: "dir/file.txt.insert:5:8: at <fail \"boom\">: error calling fail: boom\n" +
+				"    {{ fail \"boom\" }}\n" +
+				"       ^",
+		},
+		{
+			Name:     "without path",
+			Source:   source{line: 1},
+			Template: "Enter {{ fail \"boom\" }}",
+			Error: "template:1:10: at <fail \"boom\">: error calling fail: boom\n" +
+				"    Enter {{ fail \"boom\" }}\n" +
+				"             ^",
+		},
+		{
+			Name:     "parse error in partial",
+			Source:   source{path: "dir/file.txt", line: 1},
+			Template: "text",
+			Partials: []Partial{
+				{Name: "repo/_partials/common.tmpl", Text: "{{define \"broken\"}}\n  {{ .NAME | nofunc }}{{end}}"},
+			},
+			Error: "parse partial \"repo/_partials/common.tmpl\": repo/_partials/common.tmpl:2: function \"nofunc\" not defined\n      {{ .NAME | nofunc }}{{end}}",
+		},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			context.partials = f.Partials
+			_, err := evalTemplate(context, f.Source, f.Template)
+			assert.EqualError(t, err, f.Error)
+		})
+	}
+}
+
+func TestTemplateErrorInPartial(t *testing.T) {
+	context := context{
+		partials: []Partial{
+			{Name: "repo/_partials/exec.tmpl", Text: "{{define \"failing\"}}\n\t{{ fail \"boom\" }}{{end}}"},
+		},
+	}
+
+	_, err := evalTemplate(context, source{path: "file.txt", line: 1}, `{{ template "failing" }}`)
+
+	var templateErr *TemplateError
+	assert.True(t, errors.As(err, &templateErr))
+	assert.Equal(t, "repo/_partials/exec.tmpl", templateErr.Path)
+	assert.Equal(t, 2, templateErr.Line)
+	assert.Equal(t, 5, templateErr.Column)
+	assert.Equal(t, "\t   ^", caretIndent(templateErr.Excerpt, templateErr.ExcerptColumn-1)+"^")
+}
+
+func TestTemplateErrorChain(t *testing.T) {
+	_, err := evalTemplate(context{}, source{path: "file.txt", line: 1}, `{{ fail "boom" }}`)
+	err = fmt.Errorf("failed to render template: %w", err)
+	err = AddToChain(err, "render")
+	err = AddToChain(err, `action "create"`)
+
+	assert.EqualError(t, err, "failed to render template: file.txt:1:4: at <fail \"boom\">: error calling fail: boom\n"+
+		"    {{ fail \"boom\" }}\n"+
+		"       ^\n"+
+		"  in action \"create\" > render")
+	assert.True(t, errors.As(err, new(*TemplateError)))
+	assert.Equal(t, errors.New("plain"), AddToChain(errors.New("plain"), "render"))
+}
+
+func TestInsertTemplateErrors(t *testing.T) {
+	insert, err := NewInsert(source{path: "dir/file.txt.insert", line: 1}, "header\n<<<[marker={{ fail \"marker\" }}] ^start\nline 1\n{{ fail \"body\" }}\n>>>\n")
+	assert.NoError(t, err)
+
+	_, err = insert.Eval(context{}, "target.txt", "start\n")
+	assert.EqualError(t, err, "failed to render insertion template body: dir/file.txt.insert:4:4: at <fail \"body\">: error calling fail: body\n"+
+		"    {{ fail \"body\" }}\n"+
+		"       ^")
+
+	insert, err = NewInsert(source{path: "dir/file.txt.insert", line: 1}, "<<<[marker={{ fail \"marker\" }}] ^start\nbody\n>>>\n")
+	assert.NoError(t, err)
+
+	_, err = insert.Eval(context{}, "target.txt", "start\n")
+	assert.EqualError(t, err, "failed to evaluate insertion marker \"{{ fail \\\"marker\\\" }}\": dir/file.txt.insert:1:15: at <fail \"marker\">: error calling fail: marker\n"+
+		"    {{ fail \"marker\" }}\n"+
+		"       ^")
+}
@@ -3,29 +3,64 @@ package evaluation
 import (
 	"bytes"
 	"fmt"
+	"path/filepath"
+	"reflect"
 	"regexp"
 	"strings"
 	"text/template"
 
 	"github.com/Masterminds/sprig"
+	"github.com/Samasource/jen/src/internal/casing"
+	"github.com/Samasource/jen/src/internal/vfs"
 )
 
 // Context encapsulates everything required for template evaluation and rendering
 type Context interface {
 	// GetEvalVars returns a dictionary of the project's variable names mapped to
 	// their corresponding values for evaluation purposes. It does not include the
-	// process' env var.
-	GetEvalVars() map[string]interface{}
+	// process' env var. It fails if the template's computed variables cannot be
+	// evaluated.
+	GetEvalVars() (map[string]interface{}, error)
 
 	// GetPlaceholders returns a map of special placeholders that can be used instead
 	// of go template expressions, for more lightweight templating, especially for the
 	// project's name, which appears everywhere.
-	GetPlaceholders() map[string]string
+	GetPlaceholders() Placeholders
 
 	// GetShellVars returns all env vars to be used when invoking shell commands,
 	// including the current process' env vars, the project's vars and an augmented
 	// PATH var including extra bin dirs.
-	GetShellVars(includeProcessVars bool) []string
+	GetShellVars(includeProcessVars bool) ([]string, error)
+
+	// IsDryRun returns whether rendering should only report what it would do,
+	// without actually modifying anything on disk.
+	IsDryRun() bool
+
+	// GetPartials returns the shared template files whose {{define}} blocks are
+	// available to all templates, file names and prompts.
+	GetPartials() []Partial
+
+	// IsStrict returns whether references to undefined variables should fail
+	// evaluation, instead of rendering as "<no value>".
+	IsStrict() bool
+
+	// GetDelimiters returns the delimiters of template actions in rendered files
+	// and their names, unless overridden by .jendelims files or directives. They
+	// do not apply to expressions of the spec file itself.
+	GetDelimiters() Delimiters
+
+	// GetTemplateDir returns the current template's dir, from which templates can
+	// read files via the templateFile function.
+	GetTemplateDir() string
+
+	// GetProjectDir returns the current project's dir, which templates can inspect
+	// via the projectFile, fileExists, glob, readYAML, readJSON, relPath and
+	// gitRemote functions.
+	GetProjectDir() string
+
+	// GetProjectFileSystem returns the file system through which templates read
+	// project files, which, while rendering, also holds the files rendered so far.
+	GetProjectFileSystem() vfs.Reader
 }
 
 // RenderMode determines how/if rendering enabled/disabled state should change for an item
@@ -58,38 +93,155 @@ func EvalBoolExpression(context Context, expression string) (bool, error) {
 
 // EvalTemplate interpolates given template text into a final output string
 func EvalTemplate(context Context, text string) (string, error) {
-	// Escape triple braces
-	doubleOpen := strings.Repeat("{", 2)
-	doubleClose := strings.Repeat("}", 2)
-	tripleOpen := strings.Repeat("{", 3)
-	tripleClose := strings.Repeat("}", 3)
-	text = strings.ReplaceAll(text, tripleOpen, doubleOpen+"`"+doubleOpen+"`"+doubleClose)
-	text = strings.ReplaceAll(text, tripleClose, doubleOpen+"`"+doubleClose+"`"+doubleClose)
+	return evalTemplate(context, source{line: 1}, text)
+}
 
-	// Perform replacement of placeholders
-	for placeholderName, placeholderValue := range context.GetPlaceholders() {
-		text = strings.ReplaceAll(text, placeholderName, placeholderValue)
+// EvalListExpression evaluates given go template expression, which must result in a list (or nil, which
+// is considered as an empty list), and returns its items
+func EvalListExpression(context Context, expression string) ([]interface{}, error) {
+	var value interface{}
+	funcs := template.FuncMap{
+		"jenCapture": func(v interface{}) string {
+			value = v
+			return ""
+		},
+	}
+	_, err := executeTemplate(context, source{line: 1}, "{{jenCapture ("+expression+")}}", funcs)
+	if err != nil {
+		return nil, fmt.Errorf("evaluate expression %q: %w", expression, err)
+	}
+	if value == nil {
+		return nil, nil
+	}
+	list := reflect.ValueOf(value)
+	if list.Kind() != reflect.Slice && list.Kind() != reflect.Array {
+		return nil, fmt.Errorf("expression %q must evaluate to a list, not %T", expression, value)
+	}
+	items := make([]interface{}, list.Len())
+	for i := range items {
+		items[i] = list.Index(i).Interface()
+	}
+	return items, nil
+}
+
+// evalTemplate interpolates given template text, originating from given source, into a final output string
+func evalTemplate(context Context, src source, text string) (string, error) {
+	return executeTemplate(context, src, text, nil)
+}
+
+// executeTemplate interpolates given template text, originating from given source, into a final output string,
+// making given extra functions available to template, in addition to standard ones
+func executeTemplate(context Context, src source, text string, extraFuncs template.FuncMap) (string, error) {
+	const baseName = "base"
+	sources := make(map[string]templateSource)
+	sources[baseName] = newTemplateSource(context, src, text)
+
+	// In strict mode, missing keys fail in partials just like in template itself
+	tmpl := template.New(baseName)
+	if context.IsStrict() {
+		tmpl.Option("missingkey=error")
+	}
+
+	// Make partials' definitions available to template
+	funcs := newFuncMap(context, tmpl)
+	for name, fn := range extraFuncs {
+		funcs[name] = fn
+	}
+	tmpl.Funcs(funcs)
+	for _, partial := range context.GetPartials() {
+		// Partials are always written with default delimiters
+		partialSource := newTemplateSource(context, source{path: partial.Name, line: 1}, partial.Text)
+		sources[partial.Name] = partialSource
+		_, err := tmpl.New(partial.Name).Parse(partialSource.processed)
+		if err != nil {
+			return "", fmt.Errorf("parse partial %q: %w", partial.Name, newTemplateError(err, sources))
+		}
 	}
 
 	// Render go template
-	tmpl, err := template.New("base").Funcs(sprig.TxtFuncMap()).Parse(text)
+	tmpl, err := tmpl.Delims(src.delims.Left, src.delims.Right).Parse(sources[baseName].processed)
+	if err != nil {
+		return "", newTemplateError(err, sources)
+	}
+	vars, err := context.GetEvalVars()
 	if err != nil {
-		return "", fmt.Errorf("parse template %q: %w", text, err)
+		return "", err
+	}
+	if context.IsStrict() {
+		// Report all undefined variables at once, rather than only first one encountered during execution
+		if err := findUndefinedVars(tmpl.Tree, vars, sources[baseName]); err != nil {
+			return "", err
+		}
 	}
 	var buffer bytes.Buffer
-	err = tmpl.Execute(&buffer, context.GetEvalVars())
+	err = tmpl.Execute(&buffer, vars)
 	if err != nil {
-		return "", fmt.Errorf("evaluate template %q: %w", text, err)
+		return "", newTemplateError(err, sources)
 	}
 	return buffer.String(), nil
 }
 
+// newFuncMap returns all standard functions available to given template, that is sprig functions and jen's own
+func newFuncMap(context Context, tmpl *template.Template) template.FuncMap {
+	funcs := sprig.TxtFuncMap()
+	funcs["include"] = func(name string, data interface{}) (string, error) {
+		var buffer bytes.Buffer
+		err := tmpl.ExecuteTemplate(&buffer, name, data)
+		return buffer.String(), err
+	}
+	funcs["toCase"] = func(style string, value interface{}) (string, error) {
+		return casing.Format(fmt.Sprint(value), casing.Style(style))
+	}
+	for name, fn := range projectFuncs(context) {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
+// newTemplateSource preprocesses given template text and keeps track of the edits performed, for error reporting
+func newTemplateSource(context Context, src source, text string) templateSource {
+	processed, maps := preprocessTemplate(context, text, src.delims)
+	return templateSource{
+		source:    src,
+		original:  text,
+		processed: processed,
+		maps:      maps,
+	}
+}
+
+var tripleBracesRegexp = regexp.MustCompile(`\{\{\{|\}\}\}`)
+
+// preprocessTemplate escapes triple braces and replaces placeholders in given template text using given delimiters and
+// returns the edits performed by each of those steps
+func preprocessTemplate(context Context, text string, delims Delimiters) (string, []sourceMap) {
+	// Escape triple braces, which is only required with default delimiters
+	var escapes sourceMap
+	if delims.IsDefault() {
+		doubleOpen := strings.Repeat("{", 2)
+		doubleClose := strings.Repeat("}", 2)
+		text, escapes = replaceAll(text, tripleBracesRegexp, func(braces string) string {
+			return doubleOpen + "`" + braces[:2] + "`" + doubleClose
+		})
+	}
+
+	// Perform replacement of placeholders
+	text, replacements := context.GetPlaceholders().replace(text, delims)
+	return text, []sourceMap{escapes, replacements}
+}
+
 var doubleBracketRegexp = regexp.MustCompile(`\[\[.*]]`)
 
 // evalFileName interpolates the double-brace expressions, evaluates and removes the conditionals in double-bracket
-// expressions and returns the final file/dir name and whether it should be included in output and whether it should be
-// rendered.
-func evalFileName(context Context, name string) (string, bool, RenderMode, error) {
+// expressions of given file/dir path's base name and returns the final file/dir name and whether it should be included
+// in output and whether it should be rendered. Double-brace expressions use given delimiters instead, if not default.
+func evalFileName(context Context, path string, delims Delimiters) (string, bool, RenderMode, error) {
+	return evalName(context, path, filepath.Base(path), delims)
+}
+
+// evalName is the same as evalFileName, except that the name to evaluate is specified separately from the path of file
+// or dir, which is only used for error reporting
+func evalName(context Context, path, name string, delims Delimiters) (string, bool, RenderMode, error) {
+
 	// Double-bracket expressions (ie: "[[.option]]") in names are evaluated to determine whether the file/folder should be
 	// included in output and that expression then gets stripped from the name
 	for {
@@ -103,6 +255,7 @@ func evalFileName(context Context, name string) (string, bool, RenderMode, error
 		// Evaluate expression
 		value, err := EvalBoolExpression(context, exp)
 		if err != nil {
+			setTemplateErrorPath(err, path)
 			return "", false, DefaultMode, fmt.Errorf("failed to eval double-bracket expression in name %q: %w", name, err)
 		}
 
@@ -116,7 +269,7 @@ func evalFileName(context Context, name string) (string, bool, RenderMode, error
 	}
 
 	// Double-brace expressions (ie: "{{.name}}") in names get interpolated as expected
-	outputName, err := EvalTemplate(context, name)
+	outputName, err := evalTemplate(context, source{path: path, line: 1, delims: delims}, name)
 	if err != nil {
 		return "", false, DefaultMode, fmt.Errorf("failed to evaluate double-brace expression in name %q: %w", name, err)
 	}
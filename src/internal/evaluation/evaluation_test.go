@@ -4,32 +4,66 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/Samasource/jen/src/internal/vfs"
 	"github.com/stretchr/testify/assert"
 )
 
 type varMap = map[string]interface{}
-type strMap = map[string]string
 
 type context struct {
 	vars         varMap
-	placeholders strMap
+	placeholders Placeholders
+	dryRun       bool
+	partials     []Partial
+	strict       bool
+	delims       Delimiters
+	templateDir  string
+	projectDir   string
 }
 
-func (c context) GetEvalVars() map[string]interface{} {
-	return c.vars
+func (c context) GetEvalVars() (map[string]interface{}, error) {
+	return c.vars, nil
 }
 
-func (c context) GetPlaceholders() map[string]string {
+func (c context) GetPlaceholders() Placeholders {
 	return c.placeholders
 }
 
-func (c context) GetShellVars(includeProcessVars bool) []string {
+func (c context) GetShellVars(includeProcessVars bool) ([]string, error) {
 	vars := make([]string, len(c.vars))
 	for key, value := range c.vars {
 		entry := fmt.Sprintf("%s=%v", key, value)
 		vars = append(vars, entry)
 	}
-	return vars
+	return vars, nil
+}
+
+func (c context) IsDryRun() bool {
+	return c.dryRun
+}
+
+func (c context) GetPartials() []Partial {
+	return c.partials
+}
+
+func (c context) IsStrict() bool {
+	return c.strict
+}
+
+func (c context) GetDelimiters() Delimiters {
+	return c.delims
+}
+
+func (c context) GetTemplateDir() string {
+	return c.templateDir
+}
+
+func (c context) GetProjectDir() string {
+	return c.projectDir
+}
+
+func (c context) GetProjectFileSystem() vfs.Reader {
+	return vfs.OS{}
 }
 
 func TestEvalBoolExpression(t *testing.T) {
@@ -40,6 +74,11 @@ func TestEvalBoolExpression(t *testing.T) {
 			"TRUE_VAR":  true,
 			"FALSE_VAR": false,
 			"EMPTY_VAR": "",
+			"PORT":      8080,
+			"RATIO":     0.5,
+			"LIST":      []interface{}{"a", "b"},
+			"EMPTY":     []interface{}{},
+			"MAP":       varMap{"host": "localhost"},
 		},
 	}
 
@@ -108,6 +147,26 @@ func TestEvalBoolExpression(t *testing.T) {
 			Condition: `.VAR1`,
 			Expected:  true,
 		},
+		{
+			Condition: `gt .PORT 1024`,
+			Expected:  true,
+		},
+		{
+			Condition: `lt .RATIO 0.25`,
+			Expected:  false,
+		},
+		{
+			Condition: `has "b" .LIST`,
+			Expected:  true,
+		},
+		{
+			Condition: `.EMPTY`,
+			Expected:  false,
+		},
+		{
+			Condition: `eq .MAP.host "localhost"`,
+			Expected:  true,
+		},
 	}
 
 	for _, f := range fixtures {
@@ -134,9 +193,9 @@ func TestEvalFileName(t *testing.T) {
 			"FALSE_VAR": false,
 			"EMPTY_VAR": "",
 		},
-		placeholders: strMap{
-			"projekt": "myproject",
-			"PROJEKT": "MYPROJECT",
+		placeholders: Placeholders{
+			"projekt": {Value: "myproject"},
+			"PROJEKT": {Value: "MYPROJECT"},
 		},
 	}
 
@@ -223,7 +282,7 @@ func TestEvalFileName(t *testing.T) {
 
 	for _, f := range fixtures {
 		t.Run(f.Name, func(t *testing.T) {
-			actualName, actualInclude, actualRender, err := evalFileName(context, f.Name)
+			actualName, actualInclude, actualRender, err := evalFileName(context, f.Name, Delimiters{})
 
 			if f.Error != "" {
 				assert.NotNil(t, err)
@@ -237,3 +296,107 @@ func TestEvalFileName(t *testing.T) {
 		})
 	}
 }
+
+func TestEvalTemplateWithPartials(t *testing.T) {
+	context := context{
+		vars: varMap{
+			"NAME": "foo",
+		},
+		placeholders: Placeholders{
+			"projekt": {Value: "myproject"},
+		},
+		partials: []Partial{
+			{Name: "repo/_partials/common.tmpl", Text: `{{define "license"}}Licensed to projekt{{end}}{{define "greeting"}}Hello{{end}}`},
+			{Name: "template/_partials/overrides.tmpl", Text: `{{define "greeting"}}Hi {{.NAME}}{{end}}`},
+		},
+	}
+
+	fixtures := []struct {
+		Name     string
+		Template string
+		Expected string
+		Error    string
+	}{
+		{
+			Name:     "template action",
+			Template: `// {{template "license"}}`,
+			Expected: "// Licensed to myproject",
+		},
+		{
+			Name:     "include func with pipeline",
+			Template: `{{include "greeting" . | upper}}`,
+			Expected: "HI FOO",
+		},
+		{
+			Name:     "undefined partial",
+			Template: `{{include "unknown" .}}`,
+			Error:    `no template "unknown"`,
+		},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			actual, err := EvalTemplate(context, f.Template)
+
+			if f.Error != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), f.Error)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, f.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestEvalTemplateWithCaseVariants(t *testing.T) {
+	context := context{
+		vars: varMap{
+			"PROJECT": "my cool app",
+		},
+		placeholders: Placeholders{
+			"my-projekt": {Value: `{{ .PROJECT | toCase "kebab" | toCase "lower" }}`},
+			"MY-PROJEKT": {Value: `{{ .PROJECT | toCase "kebab" | toCase "upper" }}`},
+			"myProjekt":  {Value: `{{ .PROJECT | toCase "camel" }}`},
+			"MyProjekt":  {Value: `{{ .PROJECT | toCase "pascal" }}`},
+			"my_projekt": {Value: `{{ .PROJECT | toCase "snake" }}`},
+		},
+	}
+
+	fixtures := []struct {
+		Name     string
+		Template string
+		Expected string
+		Error    string
+	}{
+		{
+			Name:     "case variant placeholders",
+			Template: "my-projekt MY-PROJEKT myProjekt MyProjekt my_projekt",
+			Expected: "my-cool-app MY-COOL-APP myCoolApp MyCoolApp my_cool_app",
+		},
+		{
+			Name:     "toCase func",
+			Template: `{{ .PROJECT | toCase "screaming-snake" }}`,
+			Expected: "MY_COOL_APP",
+		},
+		{
+			Name:     "unsupported style",
+			Template: `{{ .PROJECT | toCase "wavy" }}`,
+			Error:    `unsupported case style "wavy"`,
+		},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			actual, err := EvalTemplate(context, f.Template)
+
+			if f.Error != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), f.Error)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, f.Expected, actual)
+			}
+		})
+	}
+}
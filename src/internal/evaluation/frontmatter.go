@@ -0,0 +1,143 @@
+package evaluation
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// frontMatter represents the optional YAML block at the very top of a template file, delimited by "---"
+// lines, that controls how that file gets rendered and gets stripped from output
+type frontMatter struct {
+	// If is a boolean expression determining whether file should be rendered at all
+	If string `yaml:"if"`
+
+	// Path is the output path of file, relative to output dir of its parent dir, and can
+	// contain template expressions
+	Path string `yaml:"path"`
+
+	// Mode is the octal permission bits of output file (ie: "0755")
+	Mode string `yaml:"mode"`
+
+	// Overwrite is the policy to apply when output file already exists with a different content
+	Overwrite string `yaml:"overwrite"`
+
+	// Delimiters are the delimiters of template actions within file (ie: "[% %]")
+	Delimiters string `yaml:"delimiters"`
+
+	// length is the length in bytes of the whole block, including its "---" lines
+	length int
+
+	// text is the content of the block, excluding its "---" lines
+	text string
+}
+
+var frontMatterKeys = map[string]bool{
+	"if":         true,
+	"path":       true,
+	"mode":       true,
+	"overwrite":  true,
+	"delimiters": true,
+}
+
+var frontMatterRegexp = regexp.MustCompile(`(?m)\A---[ \t]*\r?\n(?s:(.*?))^---[ \t]*\r?$\n?`)
+
+var frontMatterKeyRegexp = regexp.MustCompile(`(?m)^(?:if|path|mode|overwrite|delimiters)[ \t]*:`)
+
+// parseFrontMatter returns the front matter found at the top of given template text, or nil if there is none.
+// Blocks without any key known to jen (ie: the front matter of static site generators) are not considered
+// front matter and are left as is, while blocks mixing known keys with unknown ones (ie: typos) or that are not
+// valid YAML are reported as errors.
+func parseFrontMatter(text string) (*frontMatter, error) {
+	match := frontMatterRegexp.FindStringSubmatchIndex(text)
+	if match == nil {
+		return nil, nil
+	}
+	block := text[match[2]:match[3]]
+
+	var values map[string]interface{}
+	if err := yaml.Unmarshal([]byte(block), &values); err != nil {
+		if frontMatterKeyRegexp.MatchString(block) {
+			return nil, fmt.Errorf("invalid front matter: %w", err)
+		}
+		return nil, nil
+	}
+	var unknownKeys []string
+	for key := range values {
+		if !frontMatterKeys[key] {
+			unknownKeys = append(unknownKeys, key)
+		}
+	}
+	if len(unknownKeys) > 0 {
+		if len(unknownKeys) == len(values) {
+			return nil, nil
+		}
+		sort.Strings(unknownKeys)
+		return nil, fmt.Errorf("unknown front matter property %q (expected one of: delimiters, if, mode, overwrite, path)", unknownKeys[0])
+	}
+
+	fm := &frontMatter{
+		length: match[1],
+		text:   block,
+	}
+	if err := yaml.Unmarshal([]byte(block), fm); err != nil {
+		return nil, err
+	}
+	return fm, nil
+}
+
+// source locates the value of given key within template file, for error reporting
+func (fm *frontMatter) source(path, key string, delims Delimiters) source {
+	src := source{path: path, line: 1, delims: delims}
+	loc := regexp.MustCompile(`(?m)^` + key + `[ \t]*:[ \t]*["']?`).FindStringIndex(fm.text)
+	if loc == nil {
+		return src
+	}
+	lineStart := strings.LastIndex(fm.text[:loc[0]], "\n") + 1
+	src.line = 2 + strings.Count(fm.text[:loc[0]], "\n")
+	src.column = loc[1] - lineStart
+	return src
+}
+
+// getDelimiters returns the delimiters specified in front matter, or given default ones
+func (fm *frontMatter) getDelimiters(defaultDelims Delimiters) (Delimiters, error) {
+	if fm.Delimiters == "" {
+		return defaultDelims, nil
+	}
+	return ParseDelimiters(fm.Delimiters)
+}
+
+// getMode returns the permission bits specified in front matter, if any
+func (fm *frontMatter) getMode() (os.FileMode, bool, error) {
+	if fm.Mode == "" {
+		return 0, false, nil
+	}
+	mode, err := strconv.ParseUint(fm.Mode, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, false, fmt.Errorf("invalid octal mode %q", fm.Mode)
+	}
+	return os.FileMode(mode), true, nil
+}
+
+// evalPath evaluates the output path specified in front matter, relative to given output dir, or returns
+// given default output path if none is specified
+func (fm *frontMatter) evalPath(context Context, inputPath, outputDir, defaultOutputPath string, delims Delimiters) (string, error) {
+	if fm.Path == "" {
+		return defaultOutputPath, nil
+	}
+	path, err := evalTemplate(context, fm.source(inputPath, "path", delims), fm.Path)
+	if err != nil {
+		return "", err
+	}
+	path = filepath.Clean(strings.TrimSpace(path))
+	if path == "." || filepath.IsAbs(path) || path == ".." || strings.HasPrefix(path, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid path %q (expected a file path relative to template file's own output dir)", path)
+	}
+	return filepath.Join(outputDir, path), nil
+}
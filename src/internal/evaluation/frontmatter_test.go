@@ -0,0 +1,209 @@
+package evaluation
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFrontMatter(t *testing.T) {
+	fixtures := []struct {
+		Name     string
+		Text     string
+		Expected *frontMatter
+		Error    string
+	}{
+		{
+			Name: "all properties",
+			Text: "---\nif: .PSQL\npath: \"{{ .NAME }}/main.go\"\nmode: 0755\noverwrite: skip-existing\ndelimiters: \"[% %]\"\n---\nbody\n",
+			Expected: &frontMatter{
+				If:         ".PSQL",
+				Path:       "{{ .NAME }}/main.go",
+				Mode:       "0755",
+				Overwrite:  "skip-existing",
+				Delimiters: "[% %]",
+				length:     102,
+				text:       "if: .PSQL\npath: \"{{ .NAME }}/main.go\"\nmode: 0755\noverwrite: skip-existing\ndelimiters: \"[% %]\"\n",
+			},
+		},
+		{
+			Name: "crlf line endings",
+			Text: "---\r\nif: .PSQL\r\n---\r\nbody",
+			Expected: &frontMatter{
+				If:     ".PSQL",
+				length: 21,
+				text:   "if: .PSQL\r\n",
+			},
+		},
+		{
+			Name: "empty",
+			Text: "---\n---\nbody",
+			Expected: &frontMatter{
+				length: 8,
+			},
+		},
+		{
+			Name: "no front matter",
+			Text: "body\n---\nif: .PSQL\n---\n",
+		},
+		{
+			Name: "unterminated",
+			Text: "---\nif: .PSQL\nbody\n",
+		},
+		{
+			Name: "foreign front matter",
+			Text: "---\ntitle: Hello\nlayout: post\n---\nbody\n",
+		},
+		{
+			Name:  "typo in property",
+			Text:  "---\nif: .PSQL\nmdoe: 0755\n---\nbody\n",
+			Error: `unknown front matter property "mdoe" (expected one of: delimiters, if, mode, overwrite, path)`,
+		},
+		{
+			Name:  "invalid yaml",
+			Text:  "---\nif: .PSQL\npath: [a\n---\nbody\n",
+			Error: "invalid front matter: yaml: line 2: did not find expected ',' or ']'",
+		},
+		{
+			Name: "invalid yaml without known properties",
+			Text: "---\nSome text: [a\n---\nbody\n",
+		},
+		{
+			Name: "horizontal rules",
+			Text: "---\nSome text\n---\n",
+		},
+		{
+			Name:  "invalid property type",
+			Text:  "---\npath:\n  - a\n---\nbody\n",
+			Error: "yaml: unmarshal errors:\n  line 2: cannot unmarshal !!seq into string",
+		},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			actual, err := parseFrontMatter(f.Text)
+
+			if f.Error != "" {
+				assert.EqualError(t, err, f.Error)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, f.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestRenderFrontMatter(t *testing.T) {
+	context := context{
+		vars: varMap{
+			"NAME": "foo",
+		},
+	}
+
+	fixtures := []struct {
+		Name          string
+		Template      string
+		Existing      string
+		Options       RenderOptions
+		ExpectedPath  string
+		ExpectedText  string
+		ExpectedMode  os.FileMode
+		ExpectedError string
+	}{
+		{
+			Name:         "path",
+			Template:     "---\npath: \"{{ .NAME }}/{{ .NAME }}.go\"\n---\npackage {{ .NAME }}\n",
+			ExpectedPath: "foo/foo.go",
+			ExpectedText: "package foo\n",
+			ExpectedMode: 0644,
+		},
+		{
+			Name:         "mode",
+			Template:     "---\nmode: \"0755\"\n---\necho {{ .NAME }}\n",
+			Options:      RenderOptions{Modes: map[string]os.FileMode{"*": 0600}},
+			ExpectedPath: "file.txt",
+			ExpectedText: "echo foo\n",
+			ExpectedMode: 0755,
+		},
+		{
+			Name:         "overwrite",
+			Template:     "---\noverwrite: skip-existing\n---\n{{ .NAME }}\n",
+			Existing:     "existing",
+			ExpectedPath: "file.txt",
+			ExpectedText: "existing",
+			ExpectedMode: 0644,
+		},
+		{
+			Name:         "overwrite forced via command line",
+			Template:     "---\noverwrite: skip-existing\n---\n{{ .NAME }}\n",
+			Existing:     "existing",
+			Options:      RenderOptions{Overwrite: OverwriteExisting, OverwriteForced: true},
+			ExpectedPath: "file.txt",
+			ExpectedText: "foo\n",
+			ExpectedMode: 0644,
+		},
+		{
+			Name:         "binary file",
+			Template:     "---\npath: \"{{ .NAME }}.go\"\n---\n{{ .NAME }}\n",
+			Options:      RenderOptions{Binary: []string{"*.tmpl"}},
+			ExpectedPath: "file.txt",
+			ExpectedText: "---\npath: \"{{ .NAME }}.go\"\n---\n{{ .NAME }}\n",
+			ExpectedMode: 0644,
+		},
+		{
+			Name:          "path outside of output dir",
+			Template:      "---\npath: ../{{ .NAME }}.go\n---\n",
+			ExpectedError: `invalid path "../foo.go" (expected a file path relative to template file's own output dir)`,
+		},
+		{
+			Name:          "invalid mode",
+			Template:      "---\nmode: \"0999\"\n---\n",
+			ExpectedError: `invalid octal mode "0999"`,
+		},
+		{
+			Name:          "invalid overwrite policy",
+			Template:      "---\noverwrite: never\n---\n",
+			ExpectedError: `invalid overwrite policy "never"`,
+		},
+		{
+			Name:          "typo in property",
+			Template:      "---\nif: true\npaht: \"{{ .NAME }}.go\"\n---\n",
+			ExpectedError: `file.txt.tmpl": unknown front matter property "paht"`,
+		},
+		{
+			Name:          "error in path",
+			Template:      "---\nif: true\npath: \"{{ fail \\\"oops\\\" }}\"\n---\n",
+			ExpectedError: "file.txt.tmpl:3:11: at <fail \"oops\">: error calling fail: oops",
+		},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			inputDir := getTempDir()
+			outputDir := getTempDir()
+			defer removeAll(inputDir)
+			defer removeAll(outputDir)
+			assert.NoError(t, ioutil.WriteFile(filepath.Join(inputDir, "file.txt.tmpl"), []byte(f.Template), 0644))
+			if f.Existing != "" {
+				assert.NoError(t, ioutil.WriteFile(filepath.Join(outputDir, f.ExpectedPath), []byte(f.Existing), 0644))
+			}
+
+			_, err := Render(context, inputDir, outputDir, f.Options)
+
+			if f.ExpectedError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), f.ExpectedError)
+				return
+			}
+			assert.NoError(t, err)
+			outputPath := filepath.Join(outputDir, f.ExpectedPath)
+			assert.Equal(t, f.ExpectedText, readFile(outputPath))
+			info, err := os.Stat(outputPath)
+			assert.NoError(t, err)
+			assert.Equal(t, f.ExpectedMode, info.Mode().Perm())
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package evaluation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/Samasource/jen/src/internal/helpers/conversion"
+	"github.com/Samasource/jen/src/internal/vfs"
+	"gopkg.in/yaml.v2"
+)
+
+// projectFuncs returns the functions allowing templates to adapt to the project they are rendered into and to
+// read files from their own template dir. All paths are relative to project dir (or template dir, for templateFile)
+// and cannot point outside of it. Project files are read through the context's project file system, so that files
+// rendered so far by current step are also visible.
+func projectFuncs(context Context) template.FuncMap {
+	return template.FuncMap{
+		"projectFile": func(path string) (string, error) {
+			return readSandboxedFile(context.GetProjectFileSystem(), context.GetProjectDir(), "project", path)
+		},
+		"templateFile": func(path string) (string, error) {
+			return readSandboxedFile(vfs.OS{}, context.GetTemplateDir(), "template", path)
+		},
+		"fileExists": func(path string) (bool, error) {
+			fullPath, err := resolveSandboxedPath(context.GetProjectDir(), "project", path)
+			if err != nil {
+				return false, err
+			}
+			_, err = context.GetProjectFileSystem().Stat(fullPath)
+			return err == nil, nil
+		},
+		"glob": func(pattern string) ([]string, error) {
+			return globSandboxed(context.GetProjectFileSystem(), context.GetProjectDir(), "project", pattern)
+		},
+		"readYAML": func(path string) (map[string]interface{}, error) {
+			text, err := readSandboxedFile(context.GetProjectFileSystem(), context.GetProjectDir(), "project", path)
+			if err != nil {
+				return nil, err
+			}
+			var value map[interface{}]interface{}
+			if err := yaml.Unmarshal([]byte(text), &value); err != nil {
+				return nil, fmt.Errorf("failed to parse yaml file %q: %w", path, err)
+			}
+			return conversion.Normalize(value).(map[string]interface{}), nil
+		},
+		"readJSON": func(path string) (map[string]interface{}, error) {
+			text, err := readSandboxedFile(context.GetProjectFileSystem(), context.GetProjectDir(), "project", path)
+			if err != nil {
+				return nil, err
+			}
+			var value map[string]interface{}
+			if err := json.Unmarshal([]byte(text), &value); err != nil {
+				return nil, fmt.Errorf("failed to parse json file %q: %w", path, err)
+			}
+			return value, nil
+		},
+		"gitRemote": func(name ...string) (string, error) {
+			remote := "origin"
+			if len(name) > 0 {
+				remote = name[0]
+			}
+			return getGitRemoteURL(context.GetProjectFileSystem(), context.GetProjectDir(), remote)
+		},
+		"relPath": func(base, target string) (string, error) {
+			for _, path := range []string{base, target} {
+				if _, err := resolveSandboxedPath(context.GetProjectDir(), "project", path); err != nil {
+					return "", err
+				}
+			}
+			rel, err := filepath.Rel(filepath.Clean(base), filepath.Clean(target))
+			if err != nil {
+				return "", err
+			}
+			return filepath.ToSlash(rel), nil
+		},
+	}
+}
+
+// resolveSandboxedPath returns the full path of given path relative to given root dir (where empty stands for
+// current dir), making sure that it does not point outside of it, even through symlinks
+func resolveSandboxedPath(rootDir, rootName, path string) (string, error) {
+	if rootDir == "" {
+		rootDir = "."
+	}
+	if filepath.IsAbs(path) || !isWithin(".", path) {
+		return "", fmt.Errorf("path %q must be relative to %s dir and within it", path, rootName)
+	}
+	fullPath := filepath.Join(rootDir, path)
+
+	// Symlinks must not lead outside of root dir either
+	realRoot, err := filepath.EvalSymlinks(rootDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s dir: %w", rootName, err)
+	}
+	realPath, err := filepath.EvalSymlinks(fullPath)
+	if err == nil && !isWithin(realRoot, realPath) {
+		return "", fmt.Errorf("path %q must be relative to %s dir and within it", path, rootName)
+	}
+	return fullPath, nil
+}
+
+// isWithin determines whether given path, either absolute or relative to given base dir, is that dir itself or is
+// located under it
+func isWithin(baseDir, path string) bool {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+	rel, err := filepath.Rel(baseDir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// readSandboxedFile returns the content of given file of given file system, relative to given root dir
+func readSandboxedFile(fsys vfs.Reader, rootDir, rootName, path string) (string, error) {
+	fullPath, err := resolveSandboxedPath(rootDir, rootName, path)
+	if err != nil {
+		return "", err
+	}
+	buf, err := fsys.ReadFile(fullPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s file %q: %w", rootName, path, err)
+	}
+	return string(buf), nil
+}
+
+// globSandboxed returns the sorted paths, relative to given root dir, of files and dirs of given file system
+// matching given pattern
+func globSandboxed(fsys vfs.Reader, rootDir, rootName, pattern string) ([]string, error) {
+	if rootDir == "" {
+		rootDir = "."
+	}
+	fullPattern, err := resolveSandboxedPath(rootDir, rootName, pattern)
+	if err != nil {
+		return nil, err
+	}
+	matches, err := glob(fsys, fullPattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+	}
+	paths := make([]string, 0, len(matches))
+	for _, match := range matches {
+		rel, err := filepath.Rel(rootDir, match)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := resolveSandboxedPath(rootDir, rootName, rel); err != nil {
+			continue
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// glob returns the paths of files and dirs of given file system matching given pattern, like filepath.Glob
+func glob(fsys vfs.Reader, pattern string) ([]string, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	if !hasGlobMeta(pattern) {
+		if _, err := fsys.Lstat(pattern); err != nil {
+			return nil, nil
+		}
+		return []string{pattern}, nil
+	}
+
+	dir, file := filepath.Split(pattern)
+	dir = filepath.Clean(dir)
+	if !hasGlobMeta(dir) {
+		return globDir(fsys, dir, file, nil), nil
+	}
+	dirs, err := glob(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	var matches []string
+	for _, dir := range dirs {
+		matches = globDir(fsys, dir, file, matches)
+	}
+	return matches, nil
+}
+
+// globDir appends the paths of entries of given dir matching given pattern to given matches
+func globDir(fsys vfs.Reader, dir, pattern string, matches []string) []string {
+	infos, err := fsys.ReadDir(dir)
+	if err != nil {
+		return matches
+	}
+	for _, info := range infos {
+		if ok, _ := filepath.Match(pattern, info.Name()); ok {
+			matches = append(matches, filepath.Join(dir, info.Name()))
+		}
+	}
+	return matches
+}
+
+// hasGlobMeta determines whether given path contains any of the special characters of glob patterns
+func hasGlobMeta(path string) bool {
+	return strings.ContainsAny(path, `*?[\`)
+}
+
+var gitConfigSectionRegexp = regexp.MustCompile(`^\[\s*remote\s+"([^"]*)"\s*]$`)
+var gitConfigURLRegexp = regexp.MustCompile(`^url\s*=\s*(.*)$`)
+
+// getGitRemoteURL returns the URL of given remote, as configured in git repo at root of given project dir, or
+// an empty string if there is no such repo or remote
+func getGitRemoteURL(fsys vfs.Reader, projectDir, remote string) (string, error) {
+	configPath := filepath.Join(".git", "config")
+	fullPath, err := resolveSandboxedPath(projectDir, "project", configPath)
+	if err != nil {
+		return "", err
+	}
+	buf, err := fsys.ReadFile(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read project file %q: %w", configPath, err)
+	}
+
+	inRemote := false
+	for _, line := range strings.Split(string(buf), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			match := gitConfigSectionRegexp.FindStringSubmatch(line)
+			inRemote = match != nil && match[1] == remote
+			continue
+		}
+		if match := gitConfigURLRegexp.FindStringSubmatch(line); inRemote && match != nil {
+			return match[1], nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,132 @@
+package evaluation
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProjectFuncs(t *testing.T) {
+	projectDir := getTempDir()
+	templateDir := getTempDir()
+	outsideDir := getTempDir()
+	defer removeAll(projectDir)
+	defer removeAll(templateDir)
+	defer removeAll(outsideDir)
+
+	files := map[string]string{
+		filepath.Join(projectDir, "go.mod"):                "module github.com/acme/shop\n\ngo 1.16\n",
+		filepath.Join(projectDir, "config.yaml"):           "name: shop\nports:\n  - 80\n  - 443\ndb:\n  host: localhost\n",
+		filepath.Join(projectDir, "package.json"):          `{"name": "shop", "dependencies": {"react": "^17.0.0"}}`,
+		filepath.Join(projectDir, "cmd", "api", "main.go"): "package main",
+		filepath.Join(projectDir, "cmd", "cli", "main.go"): "package main",
+		filepath.Join(projectDir, ".git", "config"):        "[core]\n\tbare = false\n[remote \"origin\"]\n\turl = git@github.com:acme/shop.git\n\tfetch = +refs/heads/*:refs/remotes/origin/*\n[remote \"upstream\"]\n\turl = https://github.com/corp/shop.git\n",
+		filepath.Join(templateDir, "snippets", "license"):  "MIT",
+		filepath.Join(outsideDir, "secret.txt"):            "secret",
+	}
+	for path, content := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+	assert.NoError(t, os.Symlink(filepath.Join(outsideDir, "secret.txt"), filepath.Join(projectDir, "link.txt")))
+
+	context := context{
+		projectDir:  projectDir,
+		templateDir: templateDir,
+	}
+
+	fixtures := []struct {
+		Name     string
+		Template string
+		Expected string
+		Error    string
+	}{
+		{
+			Name:     "projectFile",
+			Template: `{{ projectFile "go.mod" | regexFind "module .*" | trimPrefix "module " }}`,
+			Expected: "github.com/acme/shop",
+		},
+		{
+			Name:     "fileExists",
+			Template: `{{ fileExists "go.mod" }} {{ fileExists "cmd" }} {{ fileExists "missing.txt" }}`,
+			Expected: "true true false",
+		},
+		{
+			Name:     "glob",
+			Template: `{{ range glob "cmd/*/main.go" }}{{ . }};{{ end }}`,
+			Expected: "cmd/api/main.go;cmd/cli/main.go;",
+		},
+		{
+			Name:     "readYAML",
+			Template: `{{ $c := readYAML "config.yaml" }}{{ $c.name }} {{ $c.db.host }} {{ index $c.ports 1 }}`,
+			Expected: "shop localhost 443",
+		},
+		{
+			Name:     "readJSON",
+			Template: `{{ $p := readJSON "package.json" }}{{ $p.name }} {{ index $p.dependencies "react" }}`,
+			Expected: "shop ^17.0.0",
+		},
+		{
+			Name:     "templateFile",
+			Template: `{{ templateFile "snippets/license" }}`,
+			Expected: "MIT",
+		},
+		{
+			Name:     "gitRemote",
+			Template: `{{ gitRemote }} {{ gitRemote "upstream" }} [{{ gitRemote "unknown" }}]`,
+			Expected: "git@github.com:acme/shop.git https://github.com/corp/shop.git []",
+		},
+		{
+			Name:     "relPath",
+			Template: `{{ relPath "cmd/api" "pkg/db" }}`,
+			Expected: "../../pkg/db",
+		},
+		{
+			Name:     "missing file",
+			Template: `{{ projectFile "missing.txt" }}`,
+			Error:    `failed to read project file "missing.txt"`,
+		},
+		{
+			Name:     "parent dir",
+			Template: `{{ projectFile "../secret.txt" }}`,
+			Error:    `path "../secret.txt" must be relative to project dir and within it`,
+		},
+		{
+			Name:     "absolute path",
+			Template: `{{ fileExists "/etc/passwd" }}`,
+			Error:    `path "/etc/passwd" must be relative to project dir and within it`,
+		},
+		{
+			Name:     "symlink outside of project",
+			Template: `{{ projectFile "link.txt" }}`,
+			Error:    `path "link.txt" must be relative to project dir and within it`,
+		},
+		{
+			Name:     "glob outside of project",
+			Template: `{{ glob "../*" }}`,
+			Error:    `path "../*" must be relative to project dir and within it`,
+		},
+		{
+			Name:     "relPath outside of project",
+			Template: `{{ relPath "cmd" "../other" }}`,
+			Error:    `path "../other" must be relative to project dir and within it`,
+		},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			actual, err := EvalTemplate(context, f.Template)
+
+			if f.Error != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), f.Error)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, f.Expected, actual)
+			}
+		})
+	}
+}
@@ -3,8 +3,10 @@ package evaluation
 import (
 	"path/filepath"
 	"sort"
+	"strings"
 	"testing"
 
+	"github.com/Samasource/jen/src/internal/vfs"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -17,9 +19,9 @@ func TestGetEntries(t *testing.T) {
 			"FALSE_VAR": false,
 			"EMPTY_VAR": "",
 		},
-		placeholders: strMap{
-			"projekt": "myproject",
-			"PROJEKT": "MYPROJECT",
+		placeholders: Placeholders{
+			"projekt": {Value: "myproject"},
+			"PROJEKT": {Value: "MYPROJECT"},
 		},
 	}
 
@@ -144,7 +146,8 @@ func TestGetEntries(t *testing.T) {
 			Files: []string{
 				"file1{{..}}.txt.tmpl",
 			},
-			Error: `failed to evaluate double-brace expression in name "file1{{..}}.txt.tmpl": parse template "file1{{..}}.txt.tmpl": template: base:1: unexpected <.> in operand`,
+			Error: "failed to evaluate double-brace expression in name \"file1{{..}}.txt.tmpl\": INPUT_DIR/file1{{..}}.txt.tmpl:1: unexpected <.> in operand\n" +
+				"    file1{{..}}.txt.tmpl",
 		},
 		{
 			Name: "replacements",
@@ -157,6 +160,16 @@ func TestGetEntries(t *testing.T) {
 				{input: "abcprojektdef.txt", output: "abcmyprojectdef.txt", mode: CopyMode},
 			},
 		},
+		{
+			Name: "partials dir is skipped",
+			Files: []string{
+				"_partials/header.tmpl",
+				"file1.txt",
+			},
+			Expected: []entry{
+				{input: "file1.txt", output: "file1.txt", mode: CopyMode},
+			},
+		},
 		{
 			Name: "empty folder names are collapsed in path",
 			Files: []string{
@@ -192,7 +205,7 @@ func TestGetEntries(t *testing.T) {
 				createEmptyFile(inputFile)
 			}
 
-			actual, err := getEntries(context, inputDir, outputDir, CopyMode)
+			actual, err := getEntries(context, vfs.OS{}, inputDir, outputDir, CopyMode, Delimiters{}, nil, nil, nil)
 			expected := getExpected(f.Expected, inputDir)
 
 			sort.SliceStable(actual, func(i, j int) bool {
@@ -204,7 +217,7 @@ func TestGetEntries(t *testing.T) {
 
 			if f.Error != "" {
 				assert.NotNil(t, err)
-				assert.Equal(t, f.Error, err.Error())
+				assert.Equal(t, strings.ReplaceAll(f.Error, "INPUT_DIR", inputDir), err.Error())
 			} else {
 				assert.Nil(t, err)
 				assert.Equal(t, expected, actual)
@@ -2,92 +2,542 @@ package evaluation
 
 import (
 	"fmt"
+	"path/filepath"
 	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Samasource/jen/src/internal/logging"
+)
+
+// Placement determines where a section's body gets inserted into target file
+type Placement string
+
+const (
+	// DefaultPlacement inserts body right after start line, or right before end line
+	DefaultPlacement Placement = ""
+
+	// BeforePlacement inserts body right before start line
+	BeforePlacement Placement = "before"
+
+	// ReplacePlacement replaces the region between start and end lines with body
+	ReplacePlacement Placement = "replace"
+
+	// AppendPlacement inserts body at end of file
+	AppendPlacement Placement = "append"
+
+	// PrependPlacement inserts body at beginning of file
+	PrependPlacement Placement = "prepend"
 )
 
 type Section struct {
 	start string
 	end   string
 	body  string
+
+	// marker identifies the section's inserted block, which then gets bracketed by marker comments
+	marker string
+
+	// comment overrides the comment delimiters of marker lines, which otherwise depend on target file type
+	comment string
+
+	// update replaces the previously inserted block when its content changed, instead of leaving it as is
+	update bool
+
+	// placement determines where body gets inserted relative to start and end lines
+	placement Placement
+
+	// nth selects the 1-based occurrence of start (or end) regex match to insert at, defaulting to first one
+	nth int
+
+	// last selects the last occurrence of start (or end) regex match to insert at
+	last bool
+
+	// all inserts body at every occurrence of start (or end) regex match
+	all bool
+
+	// create creates target file if it does not exist yet, instead of failing
+	create bool
 }
 
 type Insert struct {
 	sections []Section
+
+	// source locates insertion template, for error reporting, and determines its delimiters
+	source source
+
+	// positions locate the parts of each section within insertion template, for error reporting
+	positions []sectionPositions
+}
+
+// sectionPositions locates the parts of a section within its insertion template
+type sectionPositions struct {
+	marker source
+	start  source
+	body   source
+	end    source
 }
 
-var regex = regexp.MustCompile(`(?m)^<<< *(.*)\n((?:.*\n)*?(?:.*))\n>>> *(.*)$\n?`)
+// regex matches insertion sections. Options between square brackets must immediately follow `<<<` and be followed
+// by a space or end of line, so that start regexes beginning with a character class (ie: `<<<[A-Z]+ `) are not
+// mistaken for options.
+var regex = regexp.MustCompile(`(?m)^<<<(?:\[([^\]\n]*)\](?: +|$))? *(.*)\n((?:.*\n)*?(?:.*))\n>>> *(.*)$\n?`)
 
-func NewInsert(text string) (*Insert, error) {
-	matches := regex.FindAllStringSubmatch(text, -1)
+// NewInsert parses given text of insertion template originating from given source
+func NewInsert(src source, text string) (*Insert, error) {
+	matches := regex.FindAllStringSubmatchIndex(text, -1)
 	sections := make([]Section, len(matches))
+	positions := make([]sectionPositions, len(matches))
 	for i, match := range matches {
+		group := func(n int) string {
+			if match[2*n] == -1 {
+				return ""
+			}
+			return text[match[2*n]:match[2*n+1]]
+		}
 		section := Section{
-			start: match[1],
-			body:  match[2],
-			end:   match[3],
+			start: group(2),
+			body:  group(3),
+			end:   group(4),
 		}
-		if section.start == "" && section.end == "" {
-			return nil, fmt.Errorf("cannot omit both start and end regex")
+		if err := section.parseOptions(group(1)); err != nil {
+			return nil, err
+		}
+		if err := section.validate(); err != nil {
+			return nil, err
 		}
 		sections[i] = section
+		positions[i] = sectionPositions{
+			marker: getSource(src, text, match[2]+strings.Index(group(1), section.marker)),
+			start:  getSource(src, text, match[4]),
+			body:   getSource(src, text, match[6]),
+			end:    getSource(src, text, match[8]),
+		}
 	}
 	return &Insert{
-		sections: sections,
+		sections:  sections,
+		source:    src,
+		positions: positions,
 	}, nil
 }
 
-func (i Insert) Eval(context Context, text string) (string, error) {
+// getSource locates given offset of text originating from given source
+func getSource(src source, text string, offset int) source {
+	if offset < 0 {
+		offset = 0
+	}
+	line := strings.Count(text[:offset], "\n")
+	column := offset - (strings.LastIndex(text[:offset], "\n") + 1)
+	if line == 0 {
+		column += src.column
+	}
+	return source{
+		path:   src.path,
+		line:   src.line + line,
+		column: column,
+		delims: src.delims,
+	}
+}
+
+// parseOptions parses the comma-separated options between square brackets of section's header
+// (ie: `<<<[marker=endpoint-{{.NAME}},update] ^start`)
+func (s *Section) parseOptions(text string) error {
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	occurrences := 0
+	for _, option := range strings.Split(text, ",") {
+		key, value := option, ""
+		if i := strings.Index(option, "="); i != -1 {
+			key, value = option[:i], strings.TrimSpace(option[i+1:])
+		}
+		key = strings.TrimSpace(key)
+		switch key {
+		case "marker":
+			s.marker = value
+		case "comment":
+			s.comment = value
+		case "update":
+			s.update = true
+		case "create":
+			s.create = true
+		case string(BeforePlacement), string(ReplacePlacement), string(AppendPlacement), string(PrependPlacement):
+			if s.placement != DefaultPlacement {
+				return fmt.Errorf("conflicting insertion options %q and %q", s.placement, key)
+			}
+			s.placement = Placement(key)
+		case "nth":
+			nth, err := strconv.Atoi(value)
+			if err != nil || nth < 1 {
+				return fmt.Errorf("invalid nth insertion option %q (expected a positive integer)", value)
+			}
+			s.nth = nth
+			occurrences++
+		case "last":
+			s.last = true
+			occurrences++
+		case "all":
+			s.all = true
+			occurrences++
+		default:
+			return fmt.Errorf("unknown insertion option %q", strings.TrimSpace(option))
+		}
+	}
+	if occurrences > 1 {
+		return fmt.Errorf("only one of nth, last and all insertion options can be specified")
+	}
+	return nil
+}
+
+// validate checks that section's regexes are consistent with its options
+func (s Section) validate() error {
+	if s.update && s.marker == "" {
+		return fmt.Errorf("the update insertion option requires a marker")
+	}
+	switch s.placement {
+	case AppendPlacement, PrependPlacement:
+		if s.start != "" || s.end != "" {
+			return fmt.Errorf("the %s insertion option cannot be combined with start or end regex", s.placement)
+		}
+		if s.nth != 0 || s.last || s.all {
+			return fmt.Errorf("the %s insertion option cannot be combined with nth, last or all options", s.placement)
+		}
+	case BeforePlacement:
+		if s.start == "" || s.end != "" {
+			return fmt.Errorf("the before insertion option requires a start regex and no end regex")
+		}
+	case ReplacePlacement:
+		if s.start == "" || s.end == "" {
+			return fmt.Errorf("the replace insertion option requires both start and end regexes")
+		}
+	default:
+		if s.start == "" && s.end == "" {
+			return fmt.Errorf("cannot omit both start and end regex")
+		}
+	}
+	return nil
+}
+
+// CanCreateTarget determines whether any section allows creating target file when it does not exist
+func (i Insert) CanCreateTarget() bool {
 	for _, section := range i.sections {
-		// Determine where to insert section body into target string
-		insertionIndex, err := findInsertionIndex(text, section.start, section.end)
+		if section.create {
+			return true
+		}
+	}
+	return false
+}
+
+// Eval inserts all sections into given text of target file at given path, skipping those already inserted previously
+func (i Insert) Eval(context Context, targetPath, text string) (string, error) {
+	for s, section := range i.sections {
+		positions := i.getPositions(s)
+
+		// Evaluate section body and regexes as templates
+		body, err := evalTemplate(context, positions.body, section.body)
 		if err != nil {
-			return "", err
+			return "", fmt.Errorf("failed to render insertion template body: %w", err)
+		}
+		body += "\n"
+		start, err := evalTemplate(context, positions.start, section.start)
+		if err != nil {
+			return "", fmt.Errorf("failed to evaluate insertion start %q: %w", section.start, err)
+		}
+		end, err := evalTemplate(context, positions.end, section.end)
+		if err != nil {
+			return "", fmt.Errorf("failed to evaluate insertion end %q: %w", section.end, err)
+		}
+
+		// Update or skip block previously inserted with markers?
+		if section.marker != "" {
+			block, err := section.getMarkedBlock(context, positions.marker, targetPath, body)
+			if err != nil {
+				return "", err
+			}
+			blockStart, blockEnd, err := findMarkedBlock(text, block)
+			if err != nil {
+				return "", err
+			}
+			if blockStart != -1 {
+				previous := text[blockStart:blockEnd]
+				if previous == block.text || !section.update {
+					logging.Log("Skipping insertion of %q, as it was already inserted previously", block.begin)
+					continue
+				}
+				logging.Log("Updating previously inserted %q", block.begin)
+				text = text[:blockStart] + block.text + text[blockEnd:]
+				continue
+			}
+			body = block.text
 		}
 
-		// Evaluate section body as template
-		body, err := EvalTemplate(context, string(section.body))
+		// Ensure appended body starts on its own line
+		if section.placement == AppendPlacement && text != "" && !strings.HasSuffix(text, "\n") {
+			text += "\n"
+		}
+
+		// Determine where to insert section body into target string
+		regions, err := findInsertionRegions(text, section, start, end)
 		if err != nil {
-			return "", fmt.Errorf("failed to render insertion template body %q: %w", section.body, err)
+			return "", err
 		}
 
-		// Insert section body at given insertion index
-		text = text[:insertionIndex] + body + "\n" + text[insertionIndex:]
+		// Insert body into regions in reverse order, so that indices of previous regions remain valid
+		for r := len(regions) - 1; r >= 0; r-- {
+			region := regions[r]
+
+			// Skip body already present at insertion point?
+			if isAlreadyInserted(text, region, section, body, start, end) {
+				logging.Log("Skipping insertion of section body already present after %q and before %q", start, end)
+				continue
+			}
+
+			if section.placement == ReplacePlacement {
+				text = text[:region.start] + body + text[region.end:]
+			} else {
+				text = text[:region.end] + body + text[region.end:]
+			}
+		}
 	}
 	return text, nil
 }
 
-func findInsertionIndex(text, start, end string) (int, error) {
-	insertionIndex := 0
+// getPositions returns the positions of given section's parts, which are only unknown for inserts
+// not created by parsing a template
+func (i Insert) getPositions(section int) sectionPositions {
+	if section < len(i.positions) {
+		return i.positions[section]
+	}
+	return sectionPositions{marker: i.source, start: i.source, body: i.source, end: i.source}
+}
 
-	// Find start
-	if start != "" {
-		startRegex, err := regexp.Compile("(?m)" + start + `.*\n`)
-		if err != nil {
-			return -1, fmt.Errorf("invalid start regex %q: %w", start, err)
+// markedBlock represents a section body bracketed by begin and end marker comment lines
+type markedBlock struct {
+	begin string
+	end   string
+	text  string
+}
+
+// getMarkedBlock brackets given body with the section's marker comment lines
+func (s Section) getMarkedBlock(context Context, markerSource source, targetPath, body string) (markedBlock, error) {
+	marker, err := evalTemplate(context, markerSource, s.marker)
+	if err != nil {
+		return markedBlock{}, fmt.Errorf("failed to evaluate insertion marker %q: %w", s.marker, err)
+	}
+	prefix, suffix := getCommentDelimiters(targetPath)
+	if s.comment != "" {
+		prefix, suffix = s.comment, ""
+		if fields := strings.Fields(s.comment); len(fields) == 2 {
+			prefix, suffix = fields[0], fields[1]
 		}
-		index := startRegex.FindStringIndex(text)
-		if index == nil {
-			return -1, fmt.Errorf("could not locate insertion start %q", start)
+	}
+	if suffix != "" {
+		suffix = " " + suffix
+	}
+	begin := fmt.Sprintf("%s jen:begin %s%s", prefix, marker, suffix)
+	end := fmt.Sprintf("%s jen:end %s%s", prefix, marker, suffix)
+	return markedBlock{
+		begin: begin,
+		end:   end,
+		text:  begin + "\n" + body + end + "\n",
+	}, nil
+}
+
+// findMarkedBlock returns the start and end indices of given block's marker lines in text
+// (ignoring indentation), or -1 if not found
+func findMarkedBlock(text string, block markedBlock) (int, int, error) {
+	beginRegex := regexp.MustCompile(`(?m)^[ \t]*` + regexp.QuoteMeta(block.begin) + `[ \t]*(?:\n|\z)`)
+	beginIndex := beginRegex.FindStringIndex(text)
+	if beginIndex == nil {
+		return -1, -1, nil
+	}
+	endRegex := regexp.MustCompile(`(?m)^[ \t]*` + regexp.QuoteMeta(block.end) + `[ \t]*(?:\n|\z)`)
+	endIndex := endRegex.FindStringIndex(text[beginIndex[1]:])
+	if endIndex == nil {
+		return -1, -1, fmt.Errorf("could not locate end marker %q after begin marker %q", block.end, block.begin)
+	}
+	return beginIndex[0], beginIndex[1] + endIndex[1], nil
+}
+
+// isAlreadyInserted determines whether given body is already present at insertion point, that is anywhere within the
+// block of lines following start line or preceding end line or, when both are specified, anywhere in between. Searching
+// the whole block, rather than just insertion point, prevents bodies from being inserted again once other bodies have
+// been inserted at the same point.
+func isAlreadyInserted(text string, region region, section Section, body, start, end string) bool {
+	switch {
+	case section.placement == ReplacePlacement:
+		return text[region.start:region.end] == body
+	case section.placement == PrependPlacement:
+		return strings.HasPrefix(text, body)
+	case section.placement == AppendPlacement, section.placement == BeforePlacement:
+		return strings.HasSuffix(text[:region.end], body)
+	case section.start != "" && section.end != "":
+		return strings.Contains(text[region.start:region.end], body)
+	case section.start != "":
+		return isInBlockAfter(text, region.end, body, start)
+	default:
+		return isInBlockBefore(text, region.end, body, end)
+	}
+}
+
+// isInBlockAfter determines whether given body starts at any line from given index down to the next blank line or
+// line matching given anchor regex (ie: the start line of another occurrence)
+func isInBlockAfter(text string, index int, body, anchor string) bool {
+	anchorRegex, err := regexp.Compile(anchor)
+	if err != nil {
+		return false
+	}
+	for pos := index; pos < len(text); {
+		lineEnd := strings.Index(text[pos:], "\n")
+		if lineEnd == -1 {
+			lineEnd = len(text) - pos
+		}
+		line := text[pos : pos+lineEnd]
+		if strings.HasPrefix(text[pos:], body) {
+			return true
 		}
-		insertionIndex = index[1]
+		if strings.TrimSpace(line) == "" || anchorRegex.MatchString(line) {
+			return false
+		}
+		pos += lineEnd + 1
 	}
+	return false
+}
 
-	// Find end
-	if end != "" {
-		endRegex, err := regexp.Compile("(?m)" + end)
+// isInBlockBefore determines whether given body ends at any line from given index up to the previous blank line or
+// line matching given anchor regex (ie: the end line of another occurrence)
+func isInBlockBefore(text string, index int, body, anchor string) bool {
+	anchorRegex, err := regexp.Compile(anchor)
+	if err != nil {
+		return false
+	}
+	for pos := index; pos > 0; {
+		lineStart := strings.LastIndex(text[:pos-1], "\n") + 1
+		line := strings.TrimSuffix(text[lineStart:pos], "\n")
+		if strings.HasSuffix(text[:pos], body) {
+			return true
+		}
+		if strings.TrimSpace(line) == "" || anchorRegex.MatchString(line) {
+			return false
+		}
+		pos = lineStart
+	}
+	return false
+}
+
+// getCommentDelimiters returns the line comment delimiters appropriate for given file's type
+func getCommentDelimiters(path string) (string, string) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".go", ".js", ".jsx", ".ts", ".tsx", ".java", ".kt", ".kts", ".scala", ".groovy", ".gradle", ".c", ".h",
+		".cc", ".cpp", ".hpp", ".cs", ".swift", ".rs", ".dart", ".php", ".proto", ".scss":
+		return "//", ""
+	case ".sql", ".lua", ".hs":
+		return "--", ""
+	case ".html", ".htm", ".xml", ".md", ".vue", ".svg":
+		return "<!--", "-->"
+	case ".css":
+		return "/*", "*/"
+	default:
+		return "#", ""
+	}
+}
+
+// region represents the portion of target text delimited by start and end lines, where start index is right after
+// start line (or beginning of text) and end index is right before end line (or equal to start index, when no end regex
+// is specified). Insertion happens at end index, while replacement spans the whole region.
+type region struct {
+	start int
+	end   int
+}
+
+// findInsertionRegions returns the regions of text where to insert section's body, according to its placement and
+// selected occurrences of given start and end regexes
+func findInsertionRegions(text string, section Section, start, end string) ([]region, error) {
+	switch section.placement {
+	case PrependPlacement:
+		return []region{{0, 0}}, nil
+	case AppendPlacement:
+		return []region{{len(text), len(text)}}, nil
+	}
+
+	// Without start regex, insertion happens before selected end lines
+	if start == "" {
+		indices, err := findMatches(text, "end", end, "")
 		if err != nil {
-			return -1, fmt.Errorf("invalid end regex %q: %w", end, err)
+			return nil, err
 		}
-		index := endRegex.FindStringIndex(text[insertionIndex:])
-		if index == nil {
-			if start != "" {
-				return -1, fmt.Errorf("could not locate insertion end %q after start %q", end, start)
-			} else {
-				return -1, fmt.Errorf("could not locate insertion end %q", end)
+		indices, err = selectOccurrences(indices, section, "end", end)
+		if err != nil {
+			return nil, err
+		}
+		var regions []region
+		for _, index := range indices {
+			regions = append(regions, region{0, index[0]})
+		}
+		return regions, nil
+	}
+
+	// Find selected start lines
+	indices, err := findMatches(text, "start", start, `.*\n`)
+	if err != nil {
+		return nil, err
+	}
+	indices, err = selectOccurrences(indices, section, "start", start)
+	if err != nil {
+		return nil, err
+	}
+
+	var regions []region
+	for _, index := range indices {
+		// Insert before start line?
+		if section.placement == BeforePlacement {
+			lineStart := strings.LastIndex(text[:index[0]], "\n") + 1
+			regions = append(regions, region{lineStart, lineStart})
+			continue
+		}
+
+		// Find end line following start line
+		r := region{index[1], index[1]}
+		if end != "" {
+			endRegex, err := regexp.Compile("(?m)" + end)
+			if err != nil {
+				return nil, fmt.Errorf("invalid end regex %q: %w", end, err)
 			}
+			endIndex := endRegex.FindStringIndex(text[r.start:])
+			if endIndex == nil {
+				return nil, fmt.Errorf("could not locate insertion end %q after start %q", end, start)
+			}
+			r.end += endIndex[0]
 		}
-		insertionIndex += index[0]
+		regions = append(regions, r)
 	}
+	return regions, nil
+}
 
-	return insertionIndex, nil
+// findMatches returns the indices of all matches of given start or end regex, followed by given suffix, in text
+func findMatches(text, kind, expr, suffix string) ([][]int, error) {
+	regex, err := regexp.Compile("(?m)" + expr + suffix)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s regex %q: %w", kind, expr, err)
+	}
+	return regex.FindAllStringIndex(text, -1), nil
+}
+
+// selectOccurrences returns the matches selected by section's nth, last or all options, defaulting to first match
+func selectOccurrences(indices [][]int, section Section, kind, expr string) ([][]int, error) {
+	if len(indices) == 0 {
+		return nil, fmt.Errorf("could not locate insertion %s %q", kind, expr)
+	}
+	switch {
+	case section.all:
+		return indices, nil
+	case section.last:
+		return indices[len(indices)-1:], nil
+	case section.nth > len(indices):
+		return nil, fmt.Errorf("could not locate occurrence #%d of insertion %s %q (only %d found)", section.nth, kind, expr, len(indices))
+	case section.nth > 0:
+		return indices[section.nth-1 : section.nth], nil
+	default:
+		return indices[:1], nil
+	}
 }
@@ -153,6 +153,154 @@ body
 				},
 			},
 		},
+		{
+			name: "options",
+			text: `
+<<<[marker=endpoint-{{.NAME}}, comment=/* */, update] start
+body
+>>> end`,
+			expected: &Insert{
+				sections: []Section{
+					{
+						start:   "start",
+						body:    "body",
+						end:     "end",
+						marker:  "endpoint-{{.NAME}}",
+						comment: "/* */",
+						update:  true,
+					},
+				},
+			},
+		},
+		{
+			name: "placement and occurrence options",
+			text: `
+<<<[before, nth=2, create] start
+body
+>>>`,
+			expected: &Insert{
+				sections: []Section{
+					{
+						start:     "start",
+						body:      "body",
+						placement: BeforePlacement,
+						nth:       2,
+						create:    true,
+					},
+				},
+			},
+		},
+		{
+			name: "append without regexes",
+			text: `
+<<<[append]
+body
+>>>`,
+			expected: &Insert{
+				sections: []Section{
+					{
+						body:      "body",
+						placement: AppendPlacement,
+					},
+				},
+			},
+		},
+		{
+			name: "append with regex",
+			text: `
+<<<[append] start
+body
+>>>`,
+			error: "the append insertion option cannot be combined with start or end regex",
+		},
+		{
+			name: "replace without end regex",
+			text: `
+<<<[replace] start
+body
+>>>`,
+			error: "the replace insertion option requires both start and end regexes",
+		},
+		{
+			name: "before with end regex",
+			text: `
+<<<[before] start
+body
+>>> end`,
+			error: "the before insertion option requires a start regex and no end regex",
+		},
+		{
+			name: "conflicting placements",
+			text: `
+<<<[before,replace] start
+body
+>>> end`,
+			error: `conflicting insertion options "before" and "replace"`,
+		},
+		{
+			name: "conflicting occurrences",
+			text: `
+<<<[all,last] start
+body
+>>>`,
+			error: "only one of nth, last and all insertion options can be specified",
+		},
+		{
+			name: "invalid nth",
+			text: `
+<<<[nth=0] start
+body
+>>>`,
+			error: `invalid nth insertion option "0" (expected a positive integer)`,
+		},
+		{
+			name: "unknown option",
+			text: `
+<<<[unknown] start
+body
+>>> end`,
+			error: `unknown insertion option "unknown"`,
+		},
+		{
+			name: "start regex beginning with character class",
+			text: `
+<<<[A-Z]+ start
+body
+>>> [a-z]+ end`,
+			expected: &Insert{
+				sections: []Section{
+					{
+						start: "[A-Z]+ start",
+						body:  "body",
+						end:   "[a-z]+ end",
+					},
+				},
+			},
+		},
+		{
+			name: "character class separated from options",
+			text: `
+<<<[before] [A-Z] start
+body
+>>>`,
+			expected: &Insert{
+				sections: []Section{
+					{
+						start:     "[A-Z] start",
+						body:      "body",
+						placement: BeforePlacement,
+					},
+				},
+			},
+		},
+		{
+			name: "update option without marker",
+			text: `
+<<<[update] start
+body
+>>> end`,
+			error: "the update insertion option requires a marker",
+		},
 		{
 			name: "end flush on last line without regex",
 			text: `
@@ -177,7 +325,7 @@ body
 			deep.CompareUnexportedFields = true
 			defer func() { deep.CompareUnexportedFields = oldCompareUnexportedFields }()
 
-			actual, err := NewInsert(item.text)
+			actual, err := NewInsert(source{line: 1}, item.text)
 
 			if item.error != "" {
 				assert.EqualError(err, item.error)
@@ -186,7 +334,7 @@ body
 				assert.NotNil(actual)
 				if actual != nil {
 					assert.Equal(len(item.expected.sections), len(actual.sections), "number of sections")
-					if diff := deep.Equal(item.expected, actual); diff != nil {
+					if diff := deep.Equal(item.expected.sections, actual.sections); diff != nil {
 						t.Error(diff)
 					}
 				}
@@ -205,6 +353,7 @@ func TestEval(t *testing.T) {
 	items := []struct {
 		name     string
 		text     string
+		path     string
 		insert   Insert
 		expected string
 		error    string
@@ -323,7 +472,322 @@ body 2
 line 2`,
 		},
 
+		// Idempotency
+		{
+			name: "body already present after start line",
+			text: `line 1
+body 1
+line 2`,
+			insert: Insert{
+				sections: []Section{{
+					start: "^line 1",
+					body:  "body 1",
+				}},
+			},
+			expected: `line 1
+body 1
+line 2`,
+		},
+		{
+			name: "body already present before end line",
+			text: `line 1
+body 1
+line 2`,
+			insert: Insert{
+				sections: []Section{{
+					body: "body 1",
+					end:  "^line 2",
+				}},
+			},
+			expected: `line 1
+body 1
+line 2`,
+		},
+		{
+			name: "body already present anywhere between start and end lines",
+			text: `line 1
+body 1
+body 2
+line 2`,
+			insert: Insert{
+				sections: []Section{{
+					start: "^line 1",
+					body:  "body 1",
+					end:   "^line 2",
+				}},
+			},
+			expected: `line 1
+body 1
+body 2
+line 2`,
+		},
+		{
+			name: "new body with marker",
+			path: "main.go",
+			text: `line 1
+line 2`,
+			insert: Insert{
+				sections: []Section{{
+					start:  "^line 1",
+					body:   "body {{.VAR1}}",
+					marker: "block-{{.VAR1}}",
+				}},
+			},
+			expected: `line 1
+// jen:begin block-value1
+body value1
+// jen:end block-value1
+line 2`,
+		},
+		{
+			name: "body with marker already present elsewhere",
+			path: "config.yaml",
+			text: `line 1
+line 2
+  # jen:begin block
+  body
+  # jen:end block
+line 3`,
+			insert: Insert{
+				sections: []Section{{
+					start:  "^line 1",
+					body:   "body",
+					marker: "block",
+				}},
+			},
+			expected: `line 1
+line 2
+  # jen:begin block
+  body
+  # jen:end block
+line 3`,
+		},
+		{
+			name: "changed body with marker is left as is without update option",
+			path: "index.html",
+			text: `line 1
+<!-- jen:begin block -->
+old body
+<!-- jen:end block -->
+line 2`,
+			insert: Insert{
+				sections: []Section{{
+					start:  "^line 1",
+					body:   "new body",
+					marker: "block",
+				}},
+			},
+			expected: `line 1
+<!-- jen:begin block -->
+old body
+<!-- jen:end block -->
+line 2`,
+		},
+		{
+			name: "changed body with marker is updated with update option",
+			text: `line 1
+;; jen:begin block
+old body
+;; jen:end block
+line 2`,
+			insert: Insert{
+				sections: []Section{{
+					start:   "^line 1",
+					body:    "new body",
+					marker:  "block",
+					comment: ";;",
+					update:  true,
+				}},
+			},
+			expected: `line 1
+;; jen:begin block
+new body
+;; jen:end block
+line 2`,
+		},
+
+		// Placement modes
+		{
+			name: "before start line",
+			text: `line 1
+line 2
+line 3`,
+			insert: Insert{
+				sections: []Section{{
+					start:     "^line 2",
+					body:      "body",
+					placement: BeforePlacement,
+				}},
+			},
+			expected: `line 1
+body
+line 2
+line 3`,
+		},
+		{
+			name: "replace region",
+			text: `line 1
+old 1
+old 2
+line 2`,
+			insert: Insert{
+				sections: []Section{{
+					start:     "^line 1",
+					body:      "new",
+					end:       "^line 2",
+					placement: ReplacePlacement,
+				}},
+			},
+			expected: `line 1
+new
+line 2`,
+		},
+		{
+			name: "append to text without trailing newline",
+			text: `line 1`,
+			insert: Insert{
+				sections: []Section{{
+					body:      "body",
+					placement: AppendPlacement,
+				}},
+			},
+			expected: `line 1
+body
+`,
+		},
+		{
+			name: "prepend",
+			text: `line 1
+`,
+			insert: Insert{
+				sections: []Section{{
+					body:      "body",
+					placement: PrependPlacement,
+				}},
+			},
+			expected: `body
+line 1
+`,
+		},
+		{
+			name: "append already present",
+			text: `line 1
+body
+`,
+			insert: Insert{
+				sections: []Section{{
+					body:      "body",
+					placement: AppendPlacement,
+				}},
+			},
+			expected: `line 1
+body
+`,
+		},
+		{
+			name: "all occurrences",
+			text: `item
+other
+item
+`,
+			insert: Insert{
+				sections: []Section{{
+					start: "^item",
+					body:  "body",
+					all:   true,
+				}},
+			},
+			expected: `item
+body
+other
+item
+body
+`,
+		},
+		{
+			name: "last occurrence",
+			text: `item 1
+item 2
+end
+`,
+			insert: Insert{
+				sections: []Section{{
+					body: "body",
+					end:  "^item",
+					last: true,
+				}},
+			},
+			expected: `item 1
+body
+item 2
+end
+`,
+		},
+		{
+			name: "nth occurrence",
+			text: `item 1
+item 2
+item 3
+`,
+			insert: Insert{
+				sections: []Section{{
+					start: "^item",
+					body:  "body",
+					nth:   2,
+				}},
+			},
+			expected: `item 1
+item 2
+body
+item 3
+`,
+		},
+		{
+			name: "templated regexes",
+			text: `line 1
+value1:
+line 2
+`,
+			insert: Insert{
+				sections: []Section{{
+					start: "^{{.VAR1}}:",
+					body:  "body",
+				}},
+			},
+			expected: `line 1
+value1:
+body
+line 2
+`,
+		},
+
 		// Error cases
+		{
+			name: "nth occurrence not found",
+			text: `item 1
+`,
+			insert: Insert{
+				sections: []Section{{
+					start: "^item",
+					nth:   2,
+				}},
+			},
+			error: `could not locate occurrence #2 of insertion start "^item" (only 1 found)`,
+		},
+		{
+			name: "missing end marker",
+			text: `line 1
+# jen:begin block
+body`,
+			insert: Insert{
+				sections: []Section{{
+					start:  "^line 1",
+					body:   "body",
+					marker: "block",
+				}},
+			},
+			error: `could not locate end marker "# jen:end block" after begin marker "# jen:begin block"`,
+		},
 		{
 			name: "no start match",
 			text: `line 1
@@ -389,7 +853,7 @@ line 4`,
 			assert := _assert.New(t)
 			require := _require.New(t)
 
-			actual, err := item.insert.Eval(context, item.text)
+			actual, err := item.insert.Eval(context, item.path, item.text)
 
 			if item.error != "" {
 				require.Error(err)
@@ -401,3 +865,61 @@ line 4`,
 		})
 	}
 }
+
+func TestEvalInterleavedInsertions(t *testing.T) {
+	context := context{}
+	newInsert := func(start, body, end string) Insert {
+		return Insert{sections: []Section{{start: start, body: body, end: end}}}
+	}
+
+	items := []struct {
+		name     string
+		text     string
+		start    string
+		end      string
+		expected string
+	}{
+		{
+			name: "after start line",
+			text: `// routes
+
+other
+`,
+			start: "^// routes",
+			expected: `// routes
+b
+a
+
+other
+`,
+		},
+		{
+			name: "before end line",
+			text: `other
+
+// end routes
+`,
+			end: "^// end routes",
+			expected: `other
+
+a
+b
+// end routes
+`,
+		},
+	}
+
+	for _, item := range items {
+		t.Run(item.name, func(t *testing.T) {
+			require := _require.New(t)
+
+			text := item.text
+			for _, body := range []string{"a", "b", "a"} {
+				var err error
+				text, err = newInsert(item.start, body, item.end).Eval(context, "", text)
+				require.NoError(err)
+			}
+			require.Equal(item.expected, text)
+		})
+	}
+}
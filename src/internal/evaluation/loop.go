@@ -0,0 +1,117 @@
+package evaluation
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/Samasource/jen/src/internal/vfs"
+)
+
+// DefaultLoopVar is the name of the variable holding current item of a loop, when not specified
+const DefaultLoopVar = "ITEM"
+
+// WithVars returns a context identical to given one, except that given variables get added to (or override)
+// its evaluation variables
+func WithVars(context Context, vars map[string]interface{}) Context {
+	return scopedContext{
+		Context: context,
+		vars:    vars,
+	}
+}
+
+// scopedContext decorates a context with extra evaluation variables, such as the current item of a loop
+type scopedContext struct {
+	Context
+	vars map[string]interface{}
+}
+
+func (c scopedContext) GetEvalVars() (map[string]interface{}, error) {
+	parentVars, err := c.Context.GetEvalVars()
+	if err != nil {
+		return nil, err
+	}
+	vars := make(map[string]interface{})
+	for k, v := range parentVars {
+		vars[k] = v
+	}
+	for k, v := range c.vars {
+		vars[k] = v
+	}
+	return vars, nil
+}
+
+// withProjectFileSystem returns a context identical to given one, except that project files get read through
+// given file system
+func withProjectFileSystem(context Context, fsys vfs.Reader) Context {
+	return fileSystemContext{
+		Context: context,
+		fsys:    fsys,
+	}
+}
+
+// fileSystemContext decorates a context with the file system that project files get read through
+type fileSystemContext struct {
+	Context
+	fsys vfs.Reader
+}
+
+func (c fileSystemContext) GetProjectFileSystem() vfs.Reader {
+	return c.fsys
+}
+
+// getLoopContexts returns one context per item of the list resulting from given expression, each with the
+// corresponding item assigned to given variable (or DefaultLoopVar, if empty)
+func getLoopContexts(context Context, expression, varName string) ([]Context, error) {
+	items, err := EvalListExpression(context, expression)
+	if err != nil {
+		return nil, err
+	}
+	if varName == "" {
+		varName = DefaultLoopVar
+	}
+	contexts := make([]Context, len(items))
+	for i, item := range items {
+		contexts[i] = WithVars(context, map[string]interface{}{varName: item})
+	}
+	return contexts, nil
+}
+
+// setEntriesContext assigns given context to given entries, except for those already having their own (nested loops)
+func setEntriesContext(entries []entry, context Context) {
+	for i := range entries {
+		if entries[i].context == nil {
+			entries[i].context = context
+		}
+	}
+}
+
+// loop represents a range expression in a file or dir name (ie: "[[range .ENDPOINTS as EP]]")
+type loop struct {
+	// expression is the go template expression resulting in the list to iterate over
+	expression string
+
+	// varName is the name of the variable holding current item, or empty for DefaultLoopVar
+	varName string
+}
+
+var loopRegexp = regexp.MustCompile(`\[\[\s*range\s+(.*?)(?:\s+as\s+(\w+))?\s*]]`)
+
+// parseLoop returns the range expression found in given file or dir name, if any, along with the name
+// stripped of that expression
+func parseLoop(name string) (*loop, string, error) {
+	matches := loopRegexp.FindAllStringSubmatchIndex(name, -1)
+	if len(matches) == 0 {
+		return nil, name, nil
+	}
+	if len(matches) > 1 {
+		return nil, "", fmt.Errorf("only one range expression is supported in name %q", name)
+	}
+	match := matches[0]
+	l := &loop{
+		expression: name[match[2]:match[3]],
+	}
+	if match[4] != -1 {
+		l.varName = name[match[4]:match[5]]
+	}
+	return l, name[:match[0]] + name[match[1]:], nil
+}
@@ -0,0 +1,236 @@
+package evaluation
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLoop(t *testing.T) {
+	fixtures := []struct {
+		Name         string
+		Expected     *loop
+		ExpectedName string
+		Error        string
+	}{
+		{
+			Name:         "plain",
+			ExpectedName: "plain",
+		},
+		{
+			Name:         "[[range .ENDPOINTS]]",
+			Expected:     &loop{expression: ".ENDPOINTS"},
+			ExpectedName: "",
+		},
+		{
+			Name:         "{{.EP}}[[range .ENDPOINTS as EP]]",
+			Expected:     &loop{expression: ".ENDPOINTS", varName: "EP"},
+			ExpectedName: "{{.EP}}",
+		},
+		{
+			Name:         "[[ range .ENDPOINTS | sortAlpha as EP ]]dir[[.ENABLED]]",
+			Expected:     &loop{expression: ".ENDPOINTS | sortAlpha", varName: "EP"},
+			ExpectedName: "dir[[.ENABLED]]",
+		},
+		{
+			Name:  "[[range .A]][[range .B]]",
+			Error: `only one range expression is supported in name "[[range .A]][[range .B]]"`,
+		},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			actual, name, err := parseLoop(f.Name)
+
+			if f.Error != "" {
+				assert.EqualError(t, err, f.Error)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, f.Expected, actual)
+				assert.Equal(t, f.ExpectedName, name)
+			}
+		})
+	}
+}
+
+func TestEvalListExpression(t *testing.T) {
+	context := context{
+		vars: varMap{
+			"STRINGS":   []string{"b", "a"},
+			"ITEMS":     []interface{}{map[interface{}]interface{}{"name": "x"}},
+			"EMPTY":     []interface{}{},
+			"NOT_LIST":  "abc",
+			"NOT_LIST2": 123,
+		},
+	}
+
+	fixtures := []struct {
+		Expression string
+		Expected   []interface{}
+		Error      string
+	}{
+		{
+			Expression: ".STRINGS",
+			Expected:   []interface{}{"b", "a"},
+		},
+		{
+			Expression: ".STRINGS | sortAlpha",
+			Expected:   []interface{}{"a", "b"},
+		},
+		{
+			Expression: ".ITEMS",
+			Expected:   []interface{}{map[interface{}]interface{}{"name": "x"}},
+		},
+		{
+			Expression: `list "x" "y"`,
+			Expected:   []interface{}{"x", "y"},
+		},
+		{
+			Expression: ".EMPTY",
+			Expected:   []interface{}{},
+		},
+		{
+			Expression: ".UNDEFINED",
+			Expected:   nil,
+		},
+		{
+			Expression: ".NOT_LIST",
+			Error:      `expression ".NOT_LIST" must evaluate to a list, not string`,
+		},
+		{
+			Expression: ".NOT_LIST2",
+			Error:      `expression ".NOT_LIST2" must evaluate to a list, not int`,
+		},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.Expression, func(t *testing.T) {
+			actual, err := EvalListExpression(context, f.Expression)
+
+			if f.Error != "" {
+				assert.EqualError(t, err, f.Error)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, f.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestRenderLoop(t *testing.T) {
+	context := context{
+		vars: varMap{
+			"PROJECT": "shop",
+			"ENDPOINTS": []interface{}{
+				map[interface{}]interface{}{"name": "orders", "path": "/orders"},
+				map[interface{}]interface{}{"name": "users", "path": "/users"},
+			},
+			"QUEUES": []interface{}{"billing", "emails"},
+		},
+	}
+
+	fixtures := []struct {
+		Name     string
+		Files    map[string]string
+		Options  RenderOptions
+		Expected map[string]string
+	}{
+		{
+			Name: "range in dir name",
+			Files: map[string]string{
+				"{{.EP.name}}[[range .ENDPOINTS as EP]]/handler.go": "package {{.EP.name}} // {{.PROJECT}} {{.EP.path}}",
+				"main.go": "package {{.PROJECT}}",
+			},
+			Expected: map[string]string{
+				"orders/handler.go": "package orders // shop /orders",
+				"users/handler.go":  "package users // shop /users",
+				"main.go":           "package shop",
+			},
+		},
+		{
+			Name: "pure range dir with default variable",
+			Files: map[string]string{
+				"queues/[[range .QUEUES]]/{{.ITEM}}.yaml":           "queue: {{.ITEM}}",
+				"queues/[[range .QUEUES]]/{{.ITEM}}-dlq.yaml":       "queue: {{.ITEM}}-dlq",
+				"queues/[[range .QUEUES]]/{{.ITEM}}.md[[.ITEM_MD]]": "never rendered",
+			},
+			Expected: map[string]string{
+				"queues/billing.yaml":     "queue: billing",
+				"queues/billing-dlq.yaml": "queue: billing-dlq",
+				"queues/emails.yaml":      "queue: emails",
+				"queues/emails-dlq.yaml":  "queue: emails-dlq",
+			},
+		},
+		{
+			Name: "range in file name",
+			Files: map[string]string{
+				"{{.Q}}.txt[[range .QUEUES as Q]]": "{{.Q}}",
+			},
+			Expected: map[string]string{
+				"billing.txt": "billing",
+				"emails.txt":  "emails",
+			},
+		},
+		{
+			Name: "nested ranges",
+			Files: map[string]string{
+				"{{.EP.name}}[[range .ENDPOINTS as EP]]/{{.Q}}.txt[[range .QUEUES as Q]]": "{{.EP.name}}-{{.Q}}",
+			},
+			Expected: map[string]string{
+				"orders/billing.txt": "orders-billing",
+				"orders/emails.txt":  "orders-emails",
+				"users/billing.txt":  "users-billing",
+				"users/emails.txt":   "users-emails",
+			},
+		},
+		{
+			Name: "foreach option",
+			Files: map[string]string{
+				"{{.EP.name}}.go": "// {{.EP.path}}",
+			},
+			Options: RenderOptions{Foreach: ".ENDPOINTS", As: "EP"},
+			Expected: map[string]string{
+				"orders.go": "// /orders",
+				"users.go":  "// /users",
+			},
+		},
+		{
+			Name: "foreach option over undefined variable",
+			Files: map[string]string{
+				"{{.ITEM}}.go": "// {{.ITEM}}",
+			},
+			Options:  RenderOptions{Foreach: ".UNDEFINED"},
+			Expected: map[string]string{},
+		},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			// Input dir's .tmpl extension enables rendering for all its files
+			tempDir := getTempDir()
+			inputDir := filepath.Join(tempDir, "input.tmpl")
+			outputDir := getTempDir()
+			defer removeAll(tempDir)
+			defer removeAll(outputDir)
+			for name, content := range f.Files {
+				path := filepath.Join(inputDir, name)
+				assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+				assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+			}
+
+			files, err := Render(context, inputDir, outputDir, f.Options)
+			assert.NoError(t, err)
+
+			actual := make(map[string]string)
+			for _, file := range files {
+				path, err := filepath.Rel(outputDir, file.OutputPath)
+				assert.NoError(t, err)
+				actual[path] = readFile(file.OutputPath)
+			}
+			assert.Equal(t, f.Expected, actual)
+		})
+	}
+}
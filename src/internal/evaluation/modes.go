@@ -0,0 +1,77 @@
+package evaluation
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Samasource/jen/src/internal/vfs"
+)
+
+// getOutputMode determines the permission bits of an output file or dir, which mirror those of its input
+// counterpart, unless overridden by a pattern matching its output path
+func getOutputMode(fsys vfs.Reader, inputPath, outputPath string, overrides map[string]os.FileMode) (os.FileMode, error) {
+	mode, ok := matchModeOverride(outputPath, overrides)
+	if ok {
+		return mode, nil
+	}
+	info, err := fsys.Stat(inputPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to determine mode of template file %q: %w", inputPath, err)
+	}
+	return info.Mode().Perm(), nil
+}
+
+// matchModeOverride returns the mode of the most specific (longest) pattern matching given path
+func matchModeOverride(path string, overrides map[string]os.FileMode) (os.FileMode, bool) {
+	patterns := make([]string, 0, len(overrides))
+	for pattern := range overrides {
+		patterns = append(patterns, pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+
+	for _, pattern := range patterns {
+		if matchPattern(path, pattern) {
+			return overrides[pattern], true
+		}
+	}
+	return 0, false
+}
+
+// matchPattern determines whether given glob pattern matches as many trailing components of given path as the pattern
+// itself contains, so that "*.sh" matches any shell script and "bin/*" matches files in any "bin" dir.
+func matchPattern(path, pattern string) bool {
+	components := strings.Split(filepath.ToSlash(path), "/")
+	count := len(strings.Split(pattern, "/"))
+	if count > len(components) {
+		return false
+	}
+	tail := strings.Join(components[len(components)-count:], "/")
+	ok, _ := filepath.Match(pattern, tail)
+	return ok
+}
+
+// createOutputDir creates given output dir (and its missing parents) with the mode of its input counterpart
+func createOutputDir(inputDir, outputDir string, options RenderOptions) error {
+	fsys := options.output()
+	if _, err := fsys.Stat(outputDir); err == nil {
+		return nil
+	}
+	mode, err := getOutputMode(options.input(), inputDir, outputDir, options.Modes)
+	if err != nil {
+		return err
+	}
+	err = fsys.MkdirAll(outputDir, mode)
+	if err != nil {
+		return err
+	}
+	// Enforce exact mode, regardless of umask
+	return fsys.Chmod(outputDir, mode)
+}
@@ -0,0 +1,142 @@
+package evaluation
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/Samasource/jen/src/internal/diff"
+	"github.com/Samasource/jen/src/internal/logging"
+	"github.com/Samasource/jen/src/internal/vfs"
+)
+
+// OverwritePolicy determines what to do when rendering a file that already exists in output dir
+// with a different content
+type OverwritePolicy string
+
+const (
+	// DefaultOverwrite lets the caller fall back to its parent's policy, ultimately
+	// resolving to OverwriteExisting
+	DefaultOverwrite OverwritePolicy = ""
+
+	// OverwriteExisting silently replaces existing files
+	OverwriteExisting OverwritePolicy = "overwrite"
+
+	// SkipExisting leaves existing files untouched
+	SkipExisting OverwritePolicy = "skip-existing"
+
+	// FailOnExisting aborts rendering as soon as an existing file would be modified
+	FailOnExisting OverwritePolicy = "fail-on-existing"
+
+	// PromptExisting displays a diff and prompts user whether to replace each existing file
+	PromptExisting OverwritePolicy = "prompt"
+)
+
+// ParseOverwritePolicy converts given string into an overwrite policy, returning an error
+// if it is not one of the supported values
+func ParseOverwritePolicy(value string) (OverwritePolicy, error) {
+	policy := OverwritePolicy(value)
+	switch policy {
+	case DefaultOverwrite, OverwriteExisting, SkipExisting, FailOnExisting, PromptExisting:
+		return policy, nil
+	default:
+		return DefaultOverwrite, fmt.Errorf("invalid overwrite policy %q (expected one of %q, %q, %q or %q)",
+			value, OverwriteExisting, SkipExisting, FailOnExisting, PromptExisting)
+	}
+}
+
+// resolution is the outcome of resolving a potential conflict between rendered output and an existing file
+type resolution int
+
+const (
+	// writeFile means that output must be written, either because there is no existing file or because it
+	// must be replaced
+	writeFile resolution = iota
+
+	// skipFile means that existing file must be left untouched
+	skipFile
+
+	// unchangedFile means that existing file already has the same content, so that only its mode may need to
+	// be updated
+	unchangedFile
+)
+
+// resolveConflict determines, according to given policy, whether rendered output should be written to output path
+// within given file system. Only existing files with a different content are considered conflicting, so unchanged
+// files never get rewritten.
+func resolveConflict(context Context, fsys vfs.Reader, outputPath string, output output, policy OverwritePolicy) (resolution, error) {
+	existingHash, err := HashFile(fsys, outputPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return writeFile, nil
+		}
+		return skipFile, fmt.Errorf("failed to read existing output file %q: %w", outputPath, err)
+	}
+	if existingHash == output.hash {
+		logging.Log("Skipping unchanged file %q", outputPath)
+		return unchangedFile, nil
+	}
+
+	write, err := applyPolicy(context, outputPath, policy, func() error {
+		return showDiff(fsys, outputPath, output)
+	})
+	if err != nil || !write {
+		return skipFile, err
+	}
+	return writeFile, nil
+}
+
+// applyPolicy determines, according to given policy, whether an existing conflicting output file should be replaced,
+// calling given function to describe differences to user before prompting
+func applyPolicy(context Context, outputPath string, policy OverwritePolicy, describe func() error) (bool, error) {
+	switch policy {
+	case DefaultOverwrite, OverwriteExisting:
+		return true, nil
+	case SkipExisting:
+		if context.IsDryRun() {
+			logging.Plan("Would skip existing %q", outputPath)
+		}
+		logging.Log("Skipping existing file %q", outputPath)
+		return false, nil
+	case FailOnExisting:
+		return false, fmt.Errorf("output file %q already exists with a different content", outputPath)
+	case PromptExisting:
+		if context.IsDryRun() {
+			logging.Plan("Would prompt whether to overwrite %q", outputPath)
+			return false, nil
+		}
+		if err := describe(); err != nil {
+			return false, err
+		}
+		return confirmOverwrite(outputPath)
+	default:
+		return false, fmt.Errorf("unsupported overwrite policy %q", policy)
+	}
+}
+
+// showDiff displays differences between existing and rendered files
+func showDiff(fsys vfs.Reader, outputPath string, output output) error {
+	existingText, err := fsys.ReadFile(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to read existing output file %q: %w", outputPath, err)
+	}
+	if output.binary || IsBinaryContent(existingText) {
+		fmt.Printf("Binary file %q differs\n", outputPath)
+	} else {
+		fmt.Print(diff.Unified(outputPath+" (existing)", outputPath+" (rendered)", string(existingText), output.text))
+	}
+	return nil
+}
+
+// confirmOverwrite asks user whether to replace existing file
+func confirmOverwrite(outputPath string) (bool, error) {
+	prompt := &survey.Confirm{
+		Message: fmt.Sprintf("Overwrite %q?", outputPath),
+		Default: false,
+	}
+	value := false
+	if err := survey.AskOne(prompt, &value); err != nil {
+		return false, err
+	}
+	return value, nil
+}
@@ -0,0 +1,40 @@
+package evaluation
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/Samasource/jen/src/internal/constant"
+)
+
+// Partial represents a shared template file, whose {{define}} blocks can be invoked from any
+// template via {{template "name" .}} or {{include "name" .}}
+type Partial struct {
+	// Name is the partial file's path, used for reporting errors
+	Name string
+	Text string
+}
+
+// LoadPartials loads all "*.tmpl" files from the partials sub-dir of each given dir, in order, so that
+// definitions of later dirs override those of earlier ones with the same name
+func LoadPartials(dirs ...string) ([]Partial, error) {
+	var partials []Partial
+	for _, dir := range dirs {
+		paths, err := filepath.Glob(filepath.Join(dir, constant.PartialsDirName, "*.tmpl"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list partials in %q: %w", dir, err)
+		}
+		for _, path := range paths {
+			buf, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read partial %q: %w", path, err)
+			}
+			partials = append(partials, Partial{
+				Name: path,
+				Text: string(buf),
+			})
+		}
+	}
+	return partials, nil
+}
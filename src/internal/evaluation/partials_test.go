@@ -0,0 +1,36 @@
+package evaluation
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadPartials(t *testing.T) {
+	repoDir := getTempDir()
+	defer removeAll(repoDir)
+	templateDir := filepath.Join(repoDir, "templates", "hello")
+
+	files := map[string]string{
+		filepath.Join(repoDir, "_partials", "b.tmpl"):         "repo b",
+		filepath.Join(repoDir, "_partials", "a.tmpl"):         "repo a",
+		filepath.Join(repoDir, "_partials", "README.md"):      "not a partial",
+		filepath.Join(templateDir, "_partials", "local.tmpl"): "template",
+	}
+	for path, content := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+		assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+
+	partials, err := LoadPartials(repoDir, templateDir)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Partial{
+		{Name: filepath.Join(repoDir, "_partials", "a.tmpl"), Text: "repo a"},
+		{Name: filepath.Join(repoDir, "_partials", "b.tmpl"), Text: "repo b"},
+		{Name: filepath.Join(templateDir, "_partials", "local.tmpl"), Text: "template"},
+	}, partials)
+}
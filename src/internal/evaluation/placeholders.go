@@ -0,0 +1,115 @@
+package evaluation
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Placeholder is the definition of a plain-text token to be replaced in templates by the go template expression in its
+// value
+type Placeholder struct {
+	Value string
+
+	// WholeWord restricts replacement to occurrences of the token that are not directly preceded or followed by other
+	// word characters (letters, digits and underscores)
+	WholeWord bool
+}
+
+// Placeholders maps special tokens to their definitions
+type Placeholders map[string]Placeholder
+
+// Tokens returns all placeholder tokens in the order in which they take precedence during replacement, that is longest
+// first and then alphabetically
+func (p Placeholders) Tokens() []string {
+	tokens := make([]string, 0, len(p))
+	for token := range p {
+		tokens = append(tokens, token)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if len(tokens[i]) != len(tokens[j]) {
+			return len(tokens[i]) > len(tokens[j])
+		}
+		return tokens[i] < tokens[j]
+	})
+	return tokens
+}
+
+// Replace replaces all placeholder tokens in given text by their values, in a single pass, so that the outcome does not
+// depend on the order of replacements and values are never themselves subject to replacement. Where multiple tokens
+// match at the same location, the longest one wins.
+func (p Placeholders) Replace(text string) string {
+	text, _ = p.replace(text, Delimiters{})
+	return text
+}
+
+// replace performs the same as Replace, but also returns the edits performed. Values, which are written with default
+// delimiters, are translated to given delimiters.
+func (p Placeholders) replace(text string, delims Delimiters) (string, sourceMap) {
+	var alternatives []string
+	for _, token := range p.Tokens() {
+		if token == "" {
+			continue
+		}
+		expr := regexp.QuoteMeta(token)
+		if p[token].WholeWord {
+			if isWordChar(token[0]) {
+				expr = `\b` + expr
+			}
+			if isWordChar(token[len(token)-1]) {
+				expr += `\b`
+			}
+		}
+		alternatives = append(alternatives, expr)
+	}
+	if len(alternatives) == 0 {
+		return text, nil
+	}
+	regex := regexp.MustCompile(strings.Join(alternatives, "|"))
+	return replaceAll(text, regex, func(token string) string {
+		return delims.translate(p[token].Value)
+	})
+}
+
+// replaceAll replaces all matches of given regex in text with the result of given func and returns the edits performed
+func replaceAll(text string, regex *regexp.Regexp, replacement func(match string) string) (string, sourceMap) {
+	var b strings.Builder
+	var edits sourceMap
+	last := 0
+	for _, loc := range regex.FindAllStringIndex(text, -1) {
+		value := replacement(text[loc[0]:loc[1]])
+		b.WriteString(text[last:loc[0]])
+		b.WriteString(value)
+		edits = append(edits, edit{start: loc[0], end: loc[1], length: len(value)})
+		last = loc[1]
+	}
+	b.WriteString(text[last:])
+	return b.String(), edits
+}
+
+// Overlaps returns warnings about placeholders that may conflict with one another, either because a token is contained
+// within another one or because a value contains another token, which will then be left as is
+func (p Placeholders) Overlaps() []string {
+	var warnings []string
+	tokens := p.Tokens()
+	for _, token := range tokens {
+		for _, other := range tokens {
+			if other == token || other == "" {
+				continue
+			}
+			if len(other) < len(token) && strings.Contains(token, other) {
+				warnings = append(warnings, fmt.Sprintf("placeholder %q contains placeholder %q, so the longer one takes precedence wherever it appears", token, other))
+			}
+			if strings.Contains(p[token].Value, other) {
+				warnings = append(warnings, fmt.Sprintf("value of placeholder %q contains placeholder %q, which will not be replaced in it", token, other))
+			}
+		}
+	}
+	return warnings
+}
+
+// isWordChar determines whether given character matches regexp's \w class
+func isWordChar(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,104 @@
+package evaluation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlaceholdersTokens(t *testing.T) {
+	placeholders := Placeholders{
+		"projekt":  {Value: "a"},
+		"projekts": {Value: "b"},
+		"PROJEKT":  {Value: "c"},
+		"app":      {Value: "d"},
+	}
+
+	assert.Equal(t, []string{"projekts", "PROJEKT", "projekt", "app"}, placeholders.Tokens())
+}
+
+func TestPlaceholdersReplace(t *testing.T) {
+	fixtures := []struct {
+		Name         string
+		Placeholders Placeholders
+		Text         string
+		Expected     string
+	}{
+		{
+			Name:     "no placeholders",
+			Text:     "projekt",
+			Expected: "projekt",
+		},
+		{
+			Name: "longest token wins",
+			Placeholders: Placeholders{
+				"projekt":  {Value: "one"},
+				"projekts": {Value: "many"},
+			},
+			Text:     "projekt projekts",
+			Expected: "one many",
+		},
+		{
+			Name: "values are not rescanned",
+			Placeholders: Placeholders{
+				"projekt": {Value: "app"},
+				"app":     {Value: "projekt"},
+			},
+			Text:     "projekt app",
+			Expected: "app projekt",
+		},
+		{
+			Name: "special regex characters",
+			Placeholders: Placeholders{
+				"(projekt)": {Value: "name"},
+			},
+			Text:     "x(projekt)y",
+			Expected: "xnamey",
+		},
+		{
+			Name: "whole word",
+			Placeholders: Placeholders{
+				"app": {Value: "svc", WholeWord: true},
+			},
+			Text:     "app happy app-name app_name my.app",
+			Expected: "svc happy svc-name app_name my.svc",
+		},
+		{
+			Name: "whole word falls back to shorter token",
+			Placeholders: Placeholders{
+				"projekt":  {Value: "one"},
+				"projekts": {Value: "many", WholeWord: true},
+			},
+			Text:     "projekts projektsx",
+			Expected: "many onesx",
+		},
+		{
+			Name: "whole word with non-word edges",
+			Placeholders: Placeholders{
+				"-projekt-": {Value: "_", WholeWord: true},
+			},
+			Text:     "my-projekt-name",
+			Expected: "my_name",
+		},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			assert.Equal(t, f.Expected, f.Placeholders.Replace(f.Text))
+		})
+	}
+}
+
+func TestPlaceholdersOverlaps(t *testing.T) {
+	placeholders := Placeholders{
+		"projekt":  {Value: "{{.PROJECT}}"},
+		"projekts": {Value: "{{.PROJECT}}s"},
+		"app":      {Value: "{{.APP}}-projekt"},
+	}
+
+	assert.Equal(t, []string{
+		`placeholder "projekts" contains placeholder "projekt", so the longer one takes precedence wherever it appears`,
+		`value of placeholder "app" contains placeholder "projekt", which will not be replaced in it`,
+	}, placeholders.Overlaps())
+	assert.Empty(t, Placeholders{"projekt": {Value: "x"}, "app": {Value: "y"}}.Overlaps())
+}
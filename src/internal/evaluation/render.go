@@ -1,138 +1,581 @@
 package evaluation
 
 import (
+	"crypto/sha256"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/Samasource/jen/src/internal/constant"
+	"github.com/Samasource/jen/src/internal/ignore"
 	"github.com/Samasource/jen/src/internal/logging"
+	"github.com/Samasource/jen/src/internal/vfs"
 )
 
+// RenderOptions represents the settings of a given render operation
+type RenderOptions struct {
+	// Overwrite determines what to do with existing output files having a different content
+	Overwrite OverwritePolicy
+
+	// OverwriteForced indicates that Overwrite was forced via command line and should also take
+	// precedence over policies specified in template files' front matter
+	OverwriteForced bool
+
+	// SkipInserts ignores insertion templates, which is useful when rendering into a
+	// directory other than the actual project dir, where insertion targets do not exist
+	SkipInserts bool
+
+	// Modes maps glob patterns to permission bits overriding those of template files and dirs
+	// with matching output paths
+	Modes map[string]os.FileMode
+
+	// Binary lists glob patterns of files to always copy byte-for-byte, in addition to
+	// those automatically detected as binary
+	Binary []string
+
+	// Ignore lists gitignore-syntax patterns, relative to input dir, of files and dirs to exclude
+	// from output, in addition to those listed in .jenignore files
+	Ignore []string
+
+	// Foreach is an expression resulting in a list, for rendering input dir once per item, or empty
+	// for rendering it only once
+	Foreach string
+
+	// As is the name of the variable holding current item when looping with Foreach, defaulting
+	// to DefaultLoopVar
+	As string
+
+	// Input is the file system to read templates from, defaulting to the OS file system
+	Input vfs.Reader
+
+	// Output is the file system to render into, defaulting to the OS file system
+	Output vfs.FileSystem
+}
+
+// input returns the file system to read templates from
+func (o RenderOptions) input() vfs.Reader {
+	if o.Input == nil {
+		return vfs.OS{}
+	}
+	return o.Input
+}
+
+// output returns the file system to render into
+func (o RenderOptions) output() vfs.FileSystem {
+	if o.Output == nil {
+		return vfs.OS{}
+	}
+	return o.Output
+}
+
+// RenderedFile describes a file rendered from a template file, for tracking purposes
+type RenderedFile struct {
+	InputPath  string
+	OutputPath string
+
+	// Hash is the SHA-256 hex digest of the rendered content
+	Hash string
+}
+
 // Render copies all files from inputDir into outputDir, rendering as templates those for which rendering is enabled
 // interpolating folder and file names appropriately and skipping folders and files for which bracket expressions
-// evaluate to false
-func Render(context Context, inputDir, outputDir string) error {
+// evaluate to false. Symlinks are recreated as symlinks, with their targets interpolated like file names. It returns
+// the list of rendered regular files, excluding insertions, which only modify existing files. Files and folders
+// matching the options' ignore patterns or those of .jenignore files found along the way are skipped.
+func Render(context Context, inputDir, outputDir string, options RenderOptions) ([]RenderedFile, error) {
+	// Templates read project files through output file system, to see files rendered so far
+	context = withProjectFileSystem(context, options.output())
+
 	// Determine if rendering should be turned on from the start
 	renderMode, _ := getRenderModeAndRemoveExtension(inputDir)
 
-	entries, err := getEntries(context, inputDir, outputDir, renderMode)
+	var ignores ignore.Stack
+	if len(options.Ignore) > 0 {
+		matcher, err := ignore.New(inputDir, options.Ignore)
+		if err != nil {
+			return nil, err
+		}
+		ignores = ignores.Push(matcher)
+	}
+
+	// In strict mode, undefined variables are collected across all file names and templates, to report them all at once
+	undefined := new(UndefinedVarsError)
+
+	entries, err := getRootEntries(context, inputDir, outputDir, renderMode, ignores, undefined, options)
 	if err != nil {
-		return fmt.Errorf("failed to determine entries to render: %w", err)
+		return nil, fmt.Errorf("failed to determine entries to render: %w", err)
 	}
 
+	var files []RenderedFile
 	for _, entry := range entries {
-		err = renderFile(context, entry.input, entry.output, entry.mode)
+		context := entry.getContext(context)
+		if entry.mode == InsertMode && options.SkipInserts {
+			logging.Log("Skipping insertion template %q", entry.input)
+			continue
+		}
+
+		// Symlinks are recreated as such, rather than copied
+		if entry.link != "" {
+			if err := checkSymlinkTarget(entry, context.GetTemplateDir(), inputDir, outputDir); err != nil {
+				return nil, err
+			}
+			if _, err := renderSymlink(context, entry, options); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		hash, err := renderFile(context, entry, options)
 		if err != nil {
-			return err
+			if undefined.add(err) {
+				continue
+			}
+			return nil, err
+		}
+		if entry.mode != InsertMode {
+			files = append(files, RenderedFile{
+				InputPath:  entry.input,
+				OutputPath: entry.output,
+				Hash:       hash,
+			})
 		}
 	}
 
-	return nil
+	if err := undefined.errorOrNil(); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// getRootEntries returns the entries to render from given input dir into given output dir, once per item of the
+// options' foreach expression, if any
+func getRootEntries(context Context, inputDir, outputDir string, mode RenderMode, ignores ignore.Stack, undefined *UndefinedVarsError, options RenderOptions) ([]entry, error) {
+	if options.Foreach == "" {
+		return getEntries(context, options.input(), inputDir, outputDir, mode, context.GetDelimiters(), options.Binary, ignores, undefined)
+	}
+
+	contexts, err := getLoopContexts(context, options.Foreach, options.As)
+	if err != nil {
+		return nil, err
+	}
+	var entries []entry
+	for _, itemContext := range contexts {
+		children, err := getEntries(itemContext, options.input(), inputDir, outputDir, mode, context.GetDelimiters(), options.Binary, ignores, undefined)
+		if err != nil {
+			return nil, err
+		}
+		setEntriesContext(children, itemContext)
+		entries = append(entries, children...)
+	}
+	return entries, nil
 }
 
 type entry struct {
 	input  string
 	output string
 	mode   RenderMode
+
+	// context is the context for rendering entry, when it differs from that of the whole render (ie: within
+	// a loop), or nil
+	context Context
+
+	// link is the evaluated target of a symlink, or empty for regular files
+	link string
+
+	// delims are the delimiters of template actions in effect for entry, unless overridden within file itself
+	delims Delimiters
+
+	// frontMatterLength is the length in bytes of the front matter block to strip from top of file, if any
+	frontMatterLength int
+
+	// overwrite is the policy specified in file's front matter, if any, overriding that of render options
+	overwrite OverwritePolicy
+
+	// perm holds the permission bits specified in file's front matter, if hasPerm is true, overriding
+	// those of render options
+	perm    os.FileMode
+	hasPerm bool
+}
+
+// getContext returns the context for rendering entry, defaulting to given one
+func (e entry) getContext(defaultContext Context) Context {
+	if e.context != nil {
+		return e.context
+	}
+	return defaultContext
 }
 
-func getEntries(context Context, inputDir, outputDir string, parentMode RenderMode) ([]entry, error) {
+// getOverwritePolicy returns the policy to apply to entry's existing output file, that is the one specified in its
+// front matter, unless another one was forced via command line
+func (e entry) getOverwritePolicy(options RenderOptions) OverwritePolicy {
+	if e.overwrite != DefaultOverwrite && !options.OverwriteForced {
+		return e.overwrite
+	}
+	return options.Overwrite
+}
+
+// getEntries returns the entries to render from given input dir into given output dir, recursively. Undefined variables
+// encountered in strict mode are collected into given error, if not nil, instead of interrupting the process.
+func getEntries(context Context, fsys vfs.Reader, inputDir, outputDir string, parentMode RenderMode, delims Delimiters, binary []string, ignores ignore.Stack, undefined *UndefinedVarsError) ([]entry, error) {
 	var entries []entry
-	infos, err := ioutil.ReadDir(inputDir)
+	infos, err := fsys.ReadDir(inputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Delimiters of .jendelims file apply to current dir and all its descendants
+	dirDelims, ok, err := loadDelimitersFile(fsys, filepath.Join(inputDir, constant.DelimitersFileName))
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		delims = dirDelims
+	}
+
+	// Patterns of .jenignore file apply to current dir and all its descendants
+	matcher, err := ignore.Load(fsys, filepath.Join(inputDir, constant.IgnoreFileName))
 	if err != nil {
 		return nil, err
 	}
+	ignores = ignores.Push(matcher)
+
 	for _, info := range infos {
-		// Determine input/output names and render mode
 		inputName := info.Name()
 		inputPath := filepath.Join(inputDir, inputName)
-		outputName, included, mode, err := evalFileName(context, inputName)
-		if err != nil {
-			return nil, err
-		}
-		outputPath := filepath.Join(outputDir, outputName)
 
-		// Skip item?
-		if !included {
+		// Skip ignored item, .jenignore or .jendelims file or partials dir?
+		if inputName == constant.IgnoreFileName ||
+			inputName == constant.DelimitersFileName ||
+			(info.IsDir() && inputName == constant.PartialsDirName) ||
+			ignores.IsIgnored(inputPath, info.IsDir()) {
+			logging.Log("Ignoring %q", inputPath)
 			continue
 		}
 
-		// Mode defaults to parent's mode
-		if mode == DefaultMode {
-			mode = parentMode
+		// Range expression in name (ie: "[[range .ENDPOINTS as EP]]") repeats item once per element of list
+		loop, name, err := parseLoop(inputName)
+		if err != nil {
+			return nil, err
 		}
-
-		// Directory?
-		if info.IsDir() {
-			if mode == InsertMode {
-				return nil, fmt.Errorf("the .insert extension is not supported for directories: %q", inputName)
+		if loop == nil {
+			children, err := getEntry(context, fsys, info, inputPath, name, outputDir, parentMode, delims, binary, ignores, undefined)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, children...)
+			continue
+		}
+		contexts, err := getLoopContexts(context, loop.expression, loop.varName)
+		if err != nil {
+			setTemplateErrorPath(err, inputPath)
+			if undefined.add(err) {
+				continue
 			}
-			children, err := getEntries(context, inputPath, outputPath, mode)
+			return nil, fmt.Errorf("failed to eval range expression in name %q: %w", inputName, err)
+		}
+		for _, itemContext := range contexts {
+			children, err := getEntry(itemContext, fsys, info, inputPath, name, outputDir, parentMode, delims, binary, ignores, undefined)
 			if err != nil {
 				return nil, err
 			}
+			setEntriesContext(children, itemContext)
 			entries = append(entries, children...)
-		} else {
-			entries = append(entries, entry{
-				input:  inputPath,
-				output: outputPath,
-				mode:   mode,
-			})
 		}
 	}
 	return entries, nil
 }
 
-func renderFile(context Context, inputPath, outputPath string, renderMode RenderMode) error {
+// getEntry returns the entries to render from given input file or dir, whose name to evaluate may differ from its
+// actual one (ie: once stripped of range expression), into given output dir, recursively for dirs.
+func getEntry(context Context, fsys vfs.Reader, info os.FileInfo, inputPath, name, outputDir string, parentMode RenderMode, delims Delimiters, binary []string, ignores ignore.Stack, undefined *UndefinedVarsError) ([]entry, error) {
+	// Determine output name and render mode
+	inputName := info.Name()
+	outputName, included, mode, err := evalName(context, inputPath, name, delims)
+	if err != nil {
+		if undefined.add(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	outputPath := filepath.Join(outputDir, outputName)
+
+	// Skip item?
+	if !included {
+		return nil, nil
+	}
+
+	// Mode defaults to parent's mode
+	if mode == DefaultMode {
+		mode = parentMode
+	}
+
+	// Directory?
+	if info.IsDir() {
+		if mode == InsertMode {
+			return nil, fmt.Errorf("the .insert extension is not supported for directories: %q", inputName)
+		}
+		return getEntries(context, fsys, inputPath, outputPath, mode, delims, binary, ignores, undefined)
+	}
+
+	// Symlink?
+	if info.Mode()&os.ModeSymlink != 0 {
+		link, err := evalSymlinkTarget(context, fsys, inputPath, delims)
+		if err != nil {
+			return nil, err
+		}
+		return []entry{{
+			input:  inputPath,
+			output: outputPath,
+			mode:   mode,
+			link:   link,
+		}}, nil
+	}
+
+	e := entry{
+		input:  inputPath,
+		output: outputPath,
+		mode:   mode,
+		delims: delims,
+	}
+	if mode == TemplateMode || mode == InsertMode {
+		included, err = applyFrontMatter(context, fsys, &e, outputDir, binary)
+		if err != nil {
+			if undefined.add(err) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("failed to apply front matter of template %q: %w", inputPath, err)
+		}
+		if !included {
+			return nil, nil
+		}
+	}
+	return []entry{e}, nil
+}
+
+// applyFrontMatter reads the front matter of given entry's template file, if any, and adjusts entry accordingly.
+// It returns whether entry should be included in output, according to front matter's condition. Only the beginning
+// of file is read, as front matter must fit within it, and binary files (matching given patterns or detected as
+// such) are skipped.
+func applyFrontMatter(context Context, fsys vfs.Reader, e *entry, outputDir string, binary []string) (bool, error) {
+	if matchBinaryPattern(e.input, binary) {
+		return true, nil
+	}
+	prefix, err := readPrefix(fsys, e.input)
+	if err != nil {
+		return false, err
+	}
+	if IsBinaryContent(prefix) {
+		return true, nil
+	}
+	fm, err := parseFrontMatter(string(prefix))
+	if err != nil || fm == nil {
+		return true, err
+	}
+	e.frontMatterLength = fm.length
+
+	if fm.If != "" {
+		included, err := EvalBoolExpression(context, fm.If)
+		if err != nil || !included {
+			return false, err
+		}
+	}
+	if e.delims, err = fm.getDelimiters(e.delims); err != nil {
+		return false, err
+	}
+	if e.output, err = fm.evalPath(context, e.input, outputDir, e.output, e.delims); err != nil {
+		return false, err
+	}
+	if e.perm, e.hasPerm, err = fm.getMode(); err != nil {
+		return false, err
+	}
+	if e.overwrite, err = ParseOverwritePolicy(fm.Overwrite); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// renderFile renders a single template file to given output path and returns the SHA-256 hex digest of
+// rendered content
+func renderFile(context Context, entry entry, options RenderOptions) (string, error) {
+	inputPath, outputPath := entry.input, entry.output
 	logging.Log("Rendering file %q -> %q", inputPath, outputPath)
 
+	// Binary files are always copied byte-for-byte
+	binary, err := isBinaryFile(options.input(), inputPath, options.Binary)
+	if err != nil {
+		return "", err
+	}
+	if binary {
+		if entry.mode == InsertMode {
+			return "", fmt.Errorf("insertion template %q cannot be a binary file", inputPath)
+		}
+		hash, err := HashFile(options.input(), inputPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read template file %q: %w", inputPath, err)
+		}
+		return hash, writeOutput(context, entry, options, output{hash: hash, binary: true})
+	}
+
 	// Read input file
-	inputText, err := ioutil.ReadFile(inputPath)
+	inputText, err := options.input().ReadFile(inputPath)
 	if err != nil {
-		return fmt.Errorf("failed to read template file %q: %w", inputPath, err)
+		return "", fmt.Errorf("failed to read template file %q: %w", inputPath, err)
 	}
 
 	// Render input as template or copy as-is
 	var outputText string
-	if renderMode == TemplateMode {
-		// Render file as template
-		outputText, err = EvalTemplate(context, string(inputText))
-		if err != nil {
-			return fmt.Errorf("failed to render template %q: %w", inputPath, err)
+	if entry.mode == TemplateMode || entry.mode == InsertMode {
+		// Front matter gets stripped from output
+		src := source{path: inputPath, line: 1, delims: entry.delims}
+		if entry.frontMatterLength > 0 {
+			src.line += strings.Count(string(inputText[:entry.frontMatterLength]), "\n")
+			inputText = inputText[entry.frontMatterLength:]
 		}
-	} else if renderMode == InsertMode {
-		// Parse insertion template
-		insert, err := NewInsert(string(inputText))
+
+		// Delimiters directive on first line of file overrides those of its dir and gets stripped from output
+		delims, text, ok, err := parseDelimitersDirective(string(inputText))
 		if err != nil {
-			return fmt.Errorf("failed to parse insertion template %q: %w", inputPath, err)
+			return "", fmt.Errorf("failed to parse delimiters directive of template %q: %w", inputPath, err)
 		}
-		// Read target file
-		targetText, err := ioutil.ReadFile(outputPath)
-		if err != nil {
-			return fmt.Errorf("failed to read insertion target file %q: %w", outputPath, err)
+		if ok {
+			src.line, src.delims = src.line+1, delims
+			inputText = []byte(text)
 		}
-		// Perform insertion
-		outputText, err = insert.Eval(context, string(targetText))
-		if err != nil {
-			return fmt.Errorf("failed to insert template %q into target file %q: %w", inputPath, outputPath, err)
+
+		if entry.mode == TemplateMode {
+			// Render file as template
+			outputText, err = evalTemplate(context, src, string(inputText))
+			if err != nil {
+				return "", fmt.Errorf("failed to render template: %w", err)
+			}
+		} else {
+			// Parse insertion template
+			insert, err := NewInsert(src, string(inputText))
+			if err != nil {
+				return "", fmt.Errorf("failed to parse insertion template %q: %w", inputPath, err)
+			}
+			// Read target file, unless it does not exist and can be created
+			targetText, err := options.output().ReadFile(outputPath)
+			if err != nil && !(os.IsNotExist(err) && insert.CanCreateTarget()) {
+				return "", fmt.Errorf("failed to read insertion target file %q: %w", outputPath, err)
+			}
+			// Perform insertion
+			outputText, err = insert.Eval(context, outputPath, string(targetText))
+			if err != nil {
+				return "", fmt.Errorf("failed to insert template %q into target file %q: %w", inputPath, outputPath, err)
+			}
 		}
 	} else {
 		// Copy file as-is
 		outputText = string(inputText)
 	}
 
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(outputText)))
+	return hash, writeOutput(context, entry, options, output{hash: hash, text: outputText})
+}
+
+// output represents the rendered content of a file, either as text or, for binary files,
+// as a reference to the input file that must be copied as-is
+type output struct {
+	hash   string
+	text   string
+	binary bool
+}
+
+// writeOutput writes rendered content to entry's output path, according to overwrite policy and file modes
+func writeOutput(context Context, entry entry, options RenderOptions, output output) error {
+	inputPath, outputPath := entry.input, entry.output
+
+	// Resolve conflicts with existing output file (insertions always modify their target file)
+	if entry.mode != InsertMode {
+		resolution, err := resolveConflict(context, options.output(), outputPath, output, entry.getOverwritePolicy(options))
+		if err != nil {
+			return err
+		}
+		switch resolution {
+		case skipFile:
+			return nil
+		case unchangedFile:
+			// Mode may still have changed in template
+			return setOutputMode(context, entry, options)
+		}
+	}
+
+	// Only report what would be done?
+	if context.IsDryRun() {
+		reportPlannedFile(options.output(), inputPath, outputPath, entry.mode)
+		return nil
+	}
+
 	// Create output dir
 	outputDir := filepath.Dir(outputPath)
-	err = os.MkdirAll(outputDir, os.ModePerm)
+	err := createOutputDir(filepath.Dir(inputPath), outputDir, options)
 	if err != nil {
 		return fmt.Errorf("failed to create output directory %q: %w", outputDir, err)
 	}
 
+	// Replace any existing symlink, rather than writing through it
+	fsys := options.output()
+	if info, err := fsys.Lstat(outputPath); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		if err := fsys.Remove(outputPath); err != nil {
+			return fmt.Errorf("failed to remove existing symlink %q: %w", outputPath, err)
+		}
+	}
+
 	// Write file
-	err = ioutil.WriteFile(outputPath, []byte(outputText), os.ModePerm)
+	if output.binary {
+		err = vfs.CopyFile(options.input(), inputPath, fsys, outputPath, 0644)
+	} else {
+		err = fsys.WriteFile(outputPath, []byte(output.text), 0644)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to write rendered output file for template %v: %w", inputPath, err)
 	}
+
+	// Insertions preserve their target file's mode
+	if entry.mode != InsertMode {
+		return setOutputMode(context, entry, options)
+	}
+	return nil
+}
+
+// setOutputMode applies the mode of entry's template file, or that overridden by step or front matter, to its
+// existing output file, unless it already has that mode
+func setOutputMode(context Context, entry entry, options RenderOptions) error {
+	mode, err := getOutputMode(options.input(), entry.input, entry.output, options.Modes)
+	if err != nil {
+		return err
+	}
+	if entry.hasPerm {
+		mode = entry.perm
+	}
+
+	fsys := options.output()
+	info, err := fsys.Stat(entry.output)
+	if err != nil {
+		return fmt.Errorf("failed to determine mode of output file %q: %w", entry.output, err)
+	}
+	if info.Mode().Perm() == mode {
+		return nil
+	}
+	if context.IsDryRun() {
+		logging.Plan("Would change mode of %q to %04o", entry.output, mode)
+		return nil
+	}
+	if err := fsys.Chmod(entry.output, mode); err != nil {
+		return fmt.Errorf("failed to set mode of output file %q: %w", entry.output, err)
+	}
 	return nil
 }
+
+// reportPlannedFile displays how given output file would be affected by rendering in dry-run mode
+func reportPlannedFile(fsys vfs.Reader, inputPath, outputPath string, renderMode RenderMode) {
+	if renderMode == InsertMode {
+		logging.Plan("Would insert %q into %q", inputPath, outputPath)
+	} else if vfs.Exists(fsys, outputPath) {
+		logging.Plan("Would overwrite %q with %q", outputPath, inputPath)
+	} else {
+		logging.Plan("Would create %q from %q", outputPath, inputPath)
+	}
+}
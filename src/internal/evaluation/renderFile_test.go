@@ -1,6 +1,8 @@
 package evaluation
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -14,9 +16,9 @@ func TestRenderFile(t *testing.T) {
 			"TRUE_VAR":  "true",
 			"EMPTY_VAR": "",
 		},
-		placeholders: map[string]string{
-			"projekt": "myproject",
-			"PROJEKT": "MYPROJECT",
+		placeholders: Placeholders{
+			"projekt": {Value: "myproject"},
+			"PROJEKT": {Value: "MYPROJECT"},
 		},
 	}
 
@@ -83,7 +85,7 @@ func TestRenderFile(t *testing.T) {
 			outputFile := getTempFile()
 			defer deleteFile(inputFile)
 			defer deleteFile(outputFile)
-			err := renderFile(context, inputFile, outputFile, f.Mode)
+			_, err := renderFile(context, entry{input: inputFile, output: outputFile, mode: f.Mode}, RenderOptions{Overwrite: OverwriteExisting})
 			actual := readFile(outputFile)
 
 			if f.Error != "" {
@@ -96,3 +98,222 @@ func TestRenderFile(t *testing.T) {
 		})
 	}
 }
+
+func TestRenderFileOverwritePolicy(t *testing.T) {
+	context := context{}
+
+	fixtures := []struct {
+		Name     string
+		Existing string
+		Policy   OverwritePolicy
+		Expected string
+		Error    string
+	}{
+		{
+			Name:     "overwrite",
+			Existing: "existing",
+			Policy:   OverwriteExisting,
+			Expected: "rendered",
+		},
+		{
+			Name:     "default policy overwrites",
+			Existing: "existing",
+			Policy:   DefaultOverwrite,
+			Expected: "rendered",
+		},
+		{
+			Name:     "skip existing",
+			Existing: "existing",
+			Policy:   SkipExisting,
+			Expected: "existing",
+		},
+		{
+			Name:     "fail on existing",
+			Existing: "existing",
+			Policy:   FailOnExisting,
+			Error:    "already exists with a different content",
+		},
+		{
+			Name:     "fail on existing ignores unchanged files",
+			Existing: "rendered",
+			Policy:   FailOnExisting,
+			Expected: "rendered",
+		},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			inputFile := writeTempFile("rendered")
+			outputFile := writeTempFile(f.Existing)
+			defer deleteFile(inputFile)
+			defer deleteFile(outputFile)
+			_, err := renderFile(context, entry{input: inputFile, output: outputFile, mode: TemplateMode}, RenderOptions{Overwrite: f.Policy})
+			actual := readFile(outputFile)
+
+			if f.Error != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), f.Error)
+				assert.Equal(t, f.Existing, actual)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, f.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestRenderFileMode(t *testing.T) {
+	context := context{}
+
+	fixtures := []struct {
+		Name      string
+		InputMode os.FileMode
+		Overrides map[string]os.FileMode
+		Expected  os.FileMode
+	}{
+		{
+			Name:      "executable",
+			InputMode: 0755,
+			Expected:  0755,
+		},
+		{
+			Name:      "read-only",
+			InputMode: 0444,
+			Expected:  0444,
+		},
+		{
+			Name:      "override",
+			InputMode: 0644,
+			Overrides: map[string]os.FileMode{"jen_test_*": 0700},
+			Expected:  0700,
+		},
+		{
+			Name:      "non-matching override",
+			InputMode: 0644,
+			Overrides: map[string]os.FileMode{"*.sh": 0700},
+			Expected:  0644,
+		},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			inputFile := writeTempFile("content")
+			outputFile := getTempFile()
+			defer deleteFile(inputFile)
+			defer deleteFile(outputFile)
+			assert.NoError(t, os.Chmod(inputFile, f.InputMode))
+
+			_, err := renderFile(context, entry{input: inputFile, output: outputFile, mode: CopyMode}, RenderOptions{Modes: f.Overrides})
+			assert.NoError(t, err)
+
+			info, err := os.Stat(outputFile)
+			assert.NoError(t, err)
+			assert.Equal(t, f.Expected, info.Mode().Perm())
+		})
+	}
+}
+
+func TestRenderFileModeOfUnchangedFile(t *testing.T) {
+	context := context{}
+	inputFile := writeTempFile("content")
+	outputFile := writeTempFile("content")
+	defer deleteFile(inputFile)
+	defer deleteFile(outputFile)
+	assert.NoError(t, os.Chmod(inputFile, 0755))
+	assert.NoError(t, os.Chmod(outputFile, 0644))
+
+	_, err := renderFile(context, entry{input: inputFile, output: outputFile, mode: CopyMode}, RenderOptions{})
+	assert.NoError(t, err)
+
+	info, err := os.Stat(outputFile)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+}
+
+func TestMatchModeOverride(t *testing.T) {
+	overrides := map[string]os.FileMode{
+		"*.sh":        0755,
+		"bin/*":       0750,
+		"bin/tool.sh": 0700,
+	}
+
+	fixtures := []struct {
+		Path     string
+		Expected os.FileMode
+		Matched  bool
+	}{
+		{Path: "/project/script.sh", Expected: 0755, Matched: true},
+		{Path: "/project/bin/run", Expected: 0750, Matched: true},
+		{Path: "/project/bin/tool.sh", Expected: 0700, Matched: true},
+		{Path: "/project/README.md", Matched: false},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.Path, func(t *testing.T) {
+			actual, ok := matchModeOverride(f.Path, overrides)
+			assert.Equal(t, f.Matched, ok)
+			assert.Equal(t, f.Expected, actual)
+		})
+	}
+}
+
+func TestRenderBinaryFile(t *testing.T) {
+	context := context{
+		vars: varMap{
+			"VAR1": "value1",
+		},
+		placeholders: Placeholders{
+			"projekt": {Value: "myproject"},
+		},
+	}
+
+	fixtures := []struct {
+		Name     string
+		Input    string
+		Binary   []string
+		Expected string
+	}{
+		{
+			Name:     "detected binary content",
+			Input:    "\x89PNG\x00{{.VAR1}} projekt {{",
+			Expected: "\x89PNG\x00{{.VAR1}} projekt {{",
+		},
+		{
+			Name:     "explicit binary pattern",
+			Input:    "{{.VAR1}} projekt",
+			Binary:   []string{"jen_test_*"},
+			Expected: "{{.VAR1}} projekt",
+		},
+		{
+			Name:     "text content",
+			Input:    "{{.VAR1}} projekt",
+			Expected: "value1 myproject",
+		},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			inputFile := writeTempFile(f.Input)
+			outputFile := getTempFile()
+			defer deleteFile(inputFile)
+			defer deleteFile(outputFile)
+
+			_, err := renderFile(context, entry{input: inputFile, output: outputFile, mode: TemplateMode}, RenderOptions{Binary: f.Binary})
+			assert.NoError(t, err)
+			assert.Equal(t, f.Expected, readFile(outputFile))
+		})
+	}
+}
+
+func TestRenderFileInsertCreate(t *testing.T) {
+	inputFile := writeTempFile("<<<[append,create]\nbody\n>>>\n")
+	defer deleteFile(inputFile)
+	outputDir := getTempDir()
+	defer removeAll(outputDir)
+	outputFile := filepath.Join(outputDir, "file.txt")
+
+	_, err := renderFile(context{}, entry{input: inputFile, output: outputFile, mode: InsertMode}, RenderOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "body\n", readFile(outputFile))
+}
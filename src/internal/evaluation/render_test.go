@@ -1,9 +1,12 @@
 package evaluation
 
 import (
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/Samasource/jen/src/internal/vfs"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -19,7 +22,9 @@ func TestRender(t *testing.T) {
 
 	names := []string{
 		"conditionals",
+		"delimiters",
 		"escaped-braces",
+		"front-matter",
 		"non-templated",
 		"templated",
 	}
@@ -28,9 +33,192 @@ func TestRender(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			outputDir := getTempDir()
 			defer removeAll(outputDir)
-			err := Render(context, filepath.Join("testdata", name, "input"), outputDir)
+			_, err := Render(context, filepath.Join("testdata", name, "input"), outputDir, RenderOptions{})
 			assert.NoError(t, err)
 			compareDirsRecursively(t, filepath.Join("testdata", name, "output"), outputDir)
 		})
 	}
 }
+
+func TestRenderToOverlay(t *testing.T) {
+	context := context{
+		vars: varMap{
+			"VAR1":      "value1",
+			"VAR2":      "value2",
+			"TRUE_VAR":  "true",
+			"EMPTY_VAR": "",
+		},
+	}
+	outputDir := getTempDir()
+	defer removeAll(outputDir)
+	output := vfs.NewOverlay(vfs.OS{})
+
+	_, err := Render(context, filepath.Join("testdata", "conditionals", "input"), outputDir, RenderOptions{
+		Output: output,
+	})
+	assert.NoError(t, err)
+
+	// Nothing should be written to disk before committing
+	infos, err := ioutil.ReadDir(outputDir)
+	assert.NoError(t, err)
+	assert.Empty(t, infos)
+
+	assert.NoError(t, output.Commit())
+	compareDirsRecursively(t, filepath.Join("testdata", "conditionals", "output"), outputDir)
+}
+
+func TestRenderReadsProjectFilesThroughOverlay(t *testing.T) {
+	inputDir := getTempDir()
+	outputDir := getTempDir()
+	defer removeAll(inputDir)
+	defer removeAll(outputDir)
+	context := context{projectDir: outputDir}
+	files := map[string]string{
+		"a.txt":      "rendered",
+		"b.txt.tmpl": `{{ projectFile "a.txt" }} {{ fileExists "a.txt" }} {{ glob "*.txt" }}`,
+	}
+	for name, content := range files {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(inputDir, name), []byte(content), 0644))
+	}
+	output := vfs.NewOverlay(vfs.OS{})
+
+	_, err := Render(context, inputDir, outputDir, RenderOptions{Output: output})
+	assert.NoError(t, err)
+
+	assert.NoError(t, output.Commit())
+	assert.Equal(t, "rendered true [a.txt]", readFile(filepath.Join(outputDir, "b.txt")))
+}
+
+func TestRenderDryRun(t *testing.T) {
+	context := context{
+		vars: varMap{
+			"VAR1":     "value1",
+			"TRUE_VAR": "true",
+		},
+		dryRun: true,
+	}
+
+	outputDir := getTempDir()
+	defer removeAll(outputDir)
+	_, err := Render(context, filepath.Join("testdata", "templated", "input"), outputDir, RenderOptions{})
+	assert.NoError(t, err)
+
+	infos, err := ioutil.ReadDir(outputDir)
+	assert.NoError(t, err)
+	assert.Empty(t, infos, "dry-run must not write any file")
+}
+
+func TestRenderSymlinks(t *testing.T) {
+	context := context{
+		vars: varMap{
+			"VAR1": "value1",
+			"VAR2": "a/b",
+		},
+	}
+
+	fixtures := []struct {
+		Name          string
+		Dir           string
+		Link          string
+		ExpectedLink  string
+		ExpectedError string
+	}{
+		{
+			Name:         "relative link",
+			Link:         "shared/config.yaml",
+			ExpectedLink: "shared/config.yaml",
+		},
+		{
+			Name:         "templated link",
+			Link:         "shared/{{.VAR1}}.yaml",
+			ExpectedLink: "shared/value1.yaml",
+		},
+		{
+			Name:         "link to shared file elsewhere in template",
+			Link:         "../shared/config.yaml",
+			ExpectedLink: "../shared/config.yaml",
+		},
+		{
+			Name:          "escaping link",
+			Link:          "../../etc/passwd",
+			ExpectedError: "outside of template dir",
+		},
+		{
+			Name:          "absolute link",
+			Link:          "/etc/passwd",
+			ExpectedError: "only relative targets are supported",
+		},
+		{
+			Name:          "link escaping template dir",
+			Dir:           "{{.VAR2}}",
+			Link:          "../../../outside.yaml",
+			ExpectedError: "outside of template dir",
+		},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			templateDir := getTempDir()
+			outputDir := getTempDir()
+			defer removeAll(templateDir)
+			defer removeAll(outputDir)
+			inputDir := filepath.Join(templateDir, "src")
+			createEmptyFile(filepath.Join(templateDir, "shared", "config.yaml"))
+			createEmptyFile(filepath.Join(inputDir, "shared", "config.yaml"))
+			linkPath := filepath.Join(inputDir, f.Dir, "link.yaml")
+			assert.NoError(t, os.MkdirAll(filepath.Dir(linkPath), os.ModePerm))
+			assert.NoError(t, os.Symlink(f.Link, linkPath))
+			context := context
+			context.templateDir = templateDir
+
+			_, err := Render(context, inputDir, outputDir, RenderOptions{})
+
+			if f.ExpectedError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), f.ExpectedError)
+			} else {
+				assert.NoError(t, err)
+				actual, err := os.Readlink(filepath.Join(outputDir, "link.yaml"))
+				assert.NoError(t, err)
+				assert.Equal(t, f.ExpectedLink, actual)
+			}
+		})
+	}
+}
+
+func TestRenderIgnore(t *testing.T) {
+	inputDir := getTempDir()
+	outputDir := getTempDir()
+	defer removeAll(inputDir)
+	defer removeAll(outputDir)
+
+	for _, file := range []string{
+		"file.txt",
+		"file.txt.swp",
+		"notes.md",
+		"docs/guide.md",
+		"sub/local.txt",
+		"sub/keep.txt",
+		"sub/keep.txt.swp",
+	} {
+		createEmptyFile(filepath.Join(inputDir, file))
+	}
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(inputDir, ".jenignore"), []byte("# Editor files\n*.swp\ndocs/\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(inputDir, "sub", ".jenignore"), []byte("local.txt\n!keep.txt.swp\n"), 0644))
+
+	_, err := Render(context{}, inputDir, outputDir, RenderOptions{
+		Ignore: []string{"/notes.md"},
+	})
+	assert.NoError(t, err)
+
+	var actual []string
+	err = filepath.Walk(outputDir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			rel, _ := filepath.Rel(outputDir, path)
+			actual = append(actual, rel)
+		}
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"file.txt", "sub/keep.txt", "sub/keep.txt.swp"}, actual)
+}
@@ -0,0 +1,123 @@
+package evaluation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template/parse"
+)
+
+// UndefinedVarsError reports all references to undefined variables found in strict mode, possibly
+// across multiple templates
+type UndefinedVarsError struct {
+	Errors []*TemplateError
+}
+
+func (e *UndefinedVarsError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	if len(messages) == 1 {
+		return messages[0]
+	}
+	return fmt.Sprintf("found %d references to undefined variables:\n%s", len(messages), strings.Join(messages, "\n"))
+}
+
+// add appends the undefined variables reported by given error to current ones and returns whether
+// given error was indeed reporting undefined variables
+func (e *UndefinedVarsError) add(err error) bool {
+	var undefinedErr *UndefinedVarsError
+	if e == nil || !errors.As(err, &undefinedErr) {
+		return false
+	}
+	e.Errors = append(e.Errors, undefinedErr.Errors...)
+	return true
+}
+
+// errorOrNil returns current error if it reports any undefined variables, or nil otherwise
+func (e *UndefinedVarsError) errorOrNil() error {
+	if e == nil || len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+// findUndefinedVars returns an error reporting all references to undefined variables in given template
+// tree, or nil if there are none. Only references to root variables are checked statically, that is
+// those of the form {{.VAR}} outside of range and with blocks, as well as those of the form {{$.VAR}}.
+func findUndefinedVars(tree *parse.Tree, vars map[string]interface{}, src templateSource) error {
+	var result UndefinedVarsError
+	walker := varRefsWalker{
+		visit: func(name string, offset int) {
+			if _, ok := vars[name]; ok {
+				return
+			}
+			reason := fmt.Sprintf("undefined variable %q", name)
+			result.Errors = append(result.Errors, src.newError(offset, true, reason, nil))
+		},
+	}
+	walker.walk(tree.Root, true)
+	return result.errorOrNil()
+}
+
+// varRefsWalker visits all references to root variables within a template tree, along with their offset
+type varRefsWalker struct {
+	visit func(name string, offset int)
+}
+
+// walk looks for variable references in given node and its children, where dotIsRoot indicates
+// whether dot still refers to root variables map
+func (w *varRefsWalker) walk(node parse.Node, dotIsRoot bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			w.walk(child, dotIsRoot)
+		}
+	case *parse.ActionNode:
+		w.walk(n.Pipe, dotIsRoot)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			w.walk(cmd, dotIsRoot)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			w.walk(arg, dotIsRoot)
+		}
+	case *parse.ChainNode:
+		w.walk(n.Node, dotIsRoot)
+	case *parse.IfNode:
+		w.walk(n.Pipe, dotIsRoot)
+		w.walk(n.List, dotIsRoot)
+		w.walk(n.ElseList, dotIsRoot)
+	case *parse.WithNode:
+		w.walk(n.Pipe, dotIsRoot)
+		w.walk(n.List, false)
+		w.walk(n.ElseList, dotIsRoot)
+	case *parse.RangeNode:
+		w.walk(n.Pipe, dotIsRoot)
+		w.walk(n.List, false)
+		w.walk(n.ElseList, dotIsRoot)
+	case *parse.TemplateNode:
+		w.walk(n.Pipe, dotIsRoot)
+	case *parse.FieldNode:
+		if dotIsRoot {
+			w.visit(n.Ident[0], startOf(n, n.Ident))
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			w.visit(n.Ident[1], startOf(n, n.Ident))
+		}
+	}
+}
+
+// startOf returns the offset of given field or variable node, whose position is actually that of its last field
+func startOf(node parse.Node, idents []string) int {
+	return int(node.Position()) - (len(node.String()) - len(idents[len(idents)-1]) - 1)
+}
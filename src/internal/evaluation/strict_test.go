@@ -0,0 +1,134 @@
+package evaluation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Samasource/jen/src/internal/vfs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvalTemplateStrict(t *testing.T) {
+	vars := varMap{
+		"NAME":  "foo",
+		"ITEMS": []string{"a", "b"},
+		"USER":  map[string]interface{}{"Login": "bar"},
+	}
+
+	fixtures := []struct {
+		Name     string
+		Template string
+		Partials []Partial
+		Strict   bool
+		Expected string
+		Error    string
+	}{
+		{
+			Name:     "not strict",
+			Template: "{{.NAME}} {{.NAMEE}}",
+			Expected: "foo <no value>",
+		},
+		{
+			Name:     "defined variables",
+			Template: "{{.NAME}} {{$.NAME}} {{range .ITEMS}}{{.}}{{end}} {{with .USER}}{{.Login}}{{end}}",
+			Strict:   true,
+			Expected: "foo foo ab bar",
+		},
+		{
+			Name:     "single undefined variable",
+			Template: "name: {{ .NAMEE | upper }}",
+			Strict:   true,
+			Error: "template:1:10: undefined variable \"NAMEE\"\n" +
+				"    name: {{ .NAMEE | upper }}\n" +
+				"             ^",
+		},
+		{
+			Name:     "all undefined variables at once",
+			Template: "{{ .A.B }}\n{{ if .C }}{{ $.D }}{{ end }}\n{{ range .ITEMS }}{{ .E }}{{ end }}",
+			Strict:   true,
+			Error: "found 3 references to undefined variables:\n" +
+				"template:1:4: undefined variable \"A\"\n" +
+				"    {{ .A.B }}\n" +
+				"       ^\n" +
+				"template:2:7: undefined variable \"C\"\n" +
+				"    {{ if .C }}{{ $.D }}{{ end }}\n" +
+				"          ^\n" +
+				"template:2:15: undefined variable \"D\"\n" +
+				"    {{ if .C }}{{ $.D }}{{ end }}\n" +
+				"                  ^",
+		},
+		{
+			Name:     "undefined nested key",
+			Template: "{{ .USER.Name }}",
+			Strict:   true,
+			Error:    `map has no entry for key "Name"`,
+		},
+		{
+			Name:     "undefined variable in partial",
+			Template: `{{ template "greeting" . }}`,
+			Partials: []Partial{{Name: "_partials/greeting.tmpl", Text: `{{ define "greeting" }}Hi {{ .NAMEE }}{{ end }}`}},
+			Strict:   true,
+			Error:    `map has no entry for key "NAMEE"`,
+		},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			context := context{vars: vars, partials: f.Partials, strict: f.Strict}
+			actual, err := EvalTemplate(context, f.Template)
+
+			if f.Error != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), f.Error)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, f.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestEvalBoolExpressionStrict(t *testing.T) {
+	context := context{
+		vars:   varMap{"FLAG": true},
+		strict: true,
+	}
+
+	value, err := EvalBoolExpression(context, ".FLAG")
+	assert.NoError(t, err)
+	assert.True(t, value)
+
+	_, err = EvalBoolExpression(context, ".FLAGG")
+	assert.True(t, errors.As(err, new(*UndefinedVarsError)))
+	assert.Contains(t, err.Error(), `undefined variable "FLAGG"`)
+}
+
+func TestRenderStrict(t *testing.T) {
+	input := vfs.NewMemory()
+	assert.NoError(t, input.MkdirAll("input/dir{{.DIRR}}", 0755))
+	assert.NoError(t, input.WriteFile("input/dir{{.DIRR}}/file.txt", []byte("ignored"), 0644))
+	assert.NoError(t, input.WriteFile("input/a.txt.tmpl", []byte("{{.NAME}}\n{{.NAMEE}}\n"), 0644))
+	assert.NoError(t, input.WriteFile("input/b.txt.tmpl", []byte("{{.OTHER}}"), 0644))
+	assert.NoError(t, input.WriteFile("input/c.txt.tmpl", []byte("{{.NAME}}"), 0644))
+	context := context{
+		vars:   varMap{"NAME": "foo"},
+		strict: true,
+	}
+
+	_, err := Render(context, "input", "output", RenderOptions{
+		Input:  input,
+		Output: vfs.NewMemory(),
+	})
+
+	var undefinedErr *UndefinedVarsError
+	assert.True(t, errors.As(err, &undefinedErr))
+	var reasons []string
+	for _, e := range undefinedErr.Errors {
+		reasons = append(reasons, e.Path+": "+e.Reason)
+	}
+	assert.Equal(t, []string{
+		`input/dir{{.DIRR}}: undefined variable "DIRR"`,
+		`input/a.txt.tmpl: undefined variable "NAMEE"`,
+		`input/b.txt.tmpl: undefined variable "OTHER"`,
+	}, reasons)
+}
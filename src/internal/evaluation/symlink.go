@@ -0,0 +1,102 @@
+package evaluation
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/Samasource/jen/src/internal/logging"
+	"github.com/Samasource/jen/src/internal/vfs"
+)
+
+// evalSymlinkTarget reads the target of given template symlink and interpolates its double-brace expressions
+// (or those using given delimiters), just like file names
+func evalSymlinkTarget(context Context, fsys vfs.Reader, inputPath string, delims Delimiters) (string, error) {
+	target, err := fsys.Readlink(inputPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read symlink %q: %w", inputPath, err)
+	}
+	outputTarget, err := evalTemplate(context, source{path: inputPath, line: 1, delims: delims}, target)
+	if err != nil {
+		return "", fmt.Errorf("failed to evaluate double-brace expression in target %q of symlink %q: %w", target, inputPath, err)
+	}
+	return outputTarget, nil
+}
+
+// checkSymlinkTarget ensures given symlink entry points within given template dir, so that templates can link to
+// shared files anywhere within themselves, but cannot produce links escaping them. The output link is also checked
+// against the location of template dir relative to rendered output dir, as interpolated dir names may place it at a
+// different depth than its template.
+func checkSymlinkTarget(entry entry, templateDir, inputDir, outputDir string) error {
+	if filepath.IsAbs(entry.link) {
+		return fmt.Errorf("symlink %q has absolute target %q, only relative targets are supported", entry.input, entry.link)
+	}
+	if !isWithinDir(templateDir, filepath.Join(filepath.Dir(entry.input), entry.link)) {
+		return fmt.Errorf("symlink %q points to %q, outside of template dir", entry.input, entry.link)
+	}
+	rel, err := filepath.Rel(inputDir, templateDir)
+	if err != nil {
+		return fmt.Errorf("failed to locate template dir %q relative to %q: %w", templateDir, inputDir, err)
+	}
+	if !isWithinDir(filepath.Join(outputDir, rel), filepath.Join(filepath.Dir(entry.output), entry.link)) {
+		return fmt.Errorf("symlink %q points to %q, outside of template dir once rendered", entry.input, entry.link)
+	}
+	return nil
+}
+
+// isWithinDir returns whether given path is located within given dir (or is that dir itself)
+func isWithinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// renderSymlink recreates a template symlink at entry's output path and returns the SHA-256 hex digest of its target
+func renderSymlink(context Context, entry entry, options RenderOptions) (string, error) {
+	inputPath, outputPath := entry.input, entry.output
+	logging.Log("Rendering symlink %q -> %q (pointing to %q)", inputPath, outputPath, entry.link)
+	if entry.mode == InsertMode {
+		return "", fmt.Errorf("insertion template %q cannot be a symlink", inputPath)
+	}
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(entry.link)))
+
+	// Resolve conflicts with existing output file or symlink
+	fsys := options.output()
+	if _, err := fsys.Lstat(outputPath); err == nil {
+		existingTarget, err := fsys.Readlink(outputPath)
+		if err == nil && existingTarget == entry.link {
+			logging.Log("Skipping unchanged symlink %q", outputPath)
+			return hash, nil
+		}
+		write, err := applyPolicy(context, outputPath, options.Overwrite, func() error {
+			fmt.Printf("Existing %q would be replaced by a symlink to %q\n", outputPath, entry.link)
+			return nil
+		})
+		if err != nil || !write {
+			return hash, err
+		}
+		if !context.IsDryRun() {
+			if err := fsys.Remove(outputPath); err != nil {
+				return "", fmt.Errorf("failed to remove existing %q: %w", outputPath, err)
+			}
+		}
+	}
+
+	// Only report what would be done?
+	if context.IsDryRun() {
+		logging.Plan("Would create symlink %q pointing to %q", outputPath, entry.link)
+		return hash, nil
+	}
+
+	// Create output dir and symlink
+	outputDir := filepath.Dir(outputPath)
+	err := createOutputDir(filepath.Dir(inputPath), outputDir, options)
+	if err != nil {
+		return "", fmt.Errorf("failed to create output directory %q: %w", outputDir, err)
+	}
+	err = fsys.Symlink(entry.link, outputPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create symlink %q: %w", outputPath, err)
+	}
+	return hash, nil
+}
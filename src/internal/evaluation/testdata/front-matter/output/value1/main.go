@@ -0,0 +1 @@
+package value1
@@ -1,35 +1,65 @@
 package exec
 
+import (
+	"fmt"
+
+	"github.com/Samasource/jen/src/internal/evaluation"
+	"github.com/Samasource/jen/src/internal/schema"
+	"github.com/Samasource/jen/src/internal/vfs"
+)
+
 // Context encapsulates everything required by implementors
 // of the Executable interface to perform their work
 type Context interface {
 	// GetVars returns a dictionary of the project's variable names mapped to
-	// their corresponding values. It does not include the process' env var.
-	// Whenever you alter this map, you are responsible for later calling
+	// their corresponding values, overlaid with those of active profile, if any.
+	// It does not include the process' env var. Whenever you alter this map, you are responsible for later calling
 	// SetVars() to save your changes back to the project file.
 	GetVars() map[string]interface{}
 
-	// SetVars saves given variables in project file.
+	// SetVars saves given variables in project file, writing modified variables
+	// to active profile, if any.
 	SetVars(vars map[string]interface{}) error
 
+	// GetProfile returns the name of the active profile, whose variables overlay
+	// base ones, or an empty string if none.
+	GetProfile() string
+
+	// IsProfileVar returns whether given variable is defined by active profile.
+	IsProfileVar(name string) bool
+
 	// IsVarOverriden returns whether given variable has been overriden via command
 	// line. This is used to skip prompting for those variables.
 	IsVarOverriden(name string) bool
 
+	// GetSecrets returns the values of the project's secret variables, which are
+	// saved in a per-user secrets file, rather than in project file, or defined
+	// as env vars, which take precedence.
+	GetSecrets() map[string]string
+
+	// SetSecret saves the value of given secret variable in the per-user secrets
+	// file.
+	SetSecret(name, value string) error
+
+	// GetVarSchema returns the types and constraints of project variables declared
+	// in template spec.
+	GetVarSchema() schema.Schema
+
 	// GetPlaceholders returns a map of special placeholders that can be used instead
 	// of go template expressions, for more lightweight templating, especially for the
 	// project's name, which appears everywhere.
-	GetPlaceholders() map[string]string
+	GetPlaceholders() evaluation.Placeholders
 
 	// GetEvalVars returns a dictionary of the project's variable names mapped to
 	// their corresponding values for evaluation purposes. It does not include the
-	// process' env var.
-	GetEvalVars() map[string]interface{}
+	// process' env var. It fails if the template's computed variables cannot be
+	// evaluated.
+	GetEvalVars() (map[string]interface{}, error)
 
 	// GetShellVars returns all env vars to be used when invoking shell commands,
 	// including the current process' env vars, the project's vars and an augmented
 	// PATH var including extra bin dirs.
-	GetShellVars(includeProcessVars bool) []string
+	GetShellVars(includeProcessVars bool) ([]string, error)
 
 	// GetAction returns action with given name within same spec file or nil if not
 	// found.
@@ -46,6 +76,31 @@ type Context interface {
 
 	// GetProjectDir returns the current project's dir
 	GetProjectDir() string
+
+	// GetProjectFileSystem returns the file system through which templates read
+	// project files
+	GetProjectFileSystem() vfs.Reader
+
+	// IsDryRun returns whether executables should only report what they would do,
+	// without actually modifying anything on disk or executing any shell command.
+	IsDryRun() bool
+
+	// GetPartials returns the shared template files whose {{define}} blocks are
+	// available to all templates, file names and prompts.
+	GetPartials() []evaluation.Partial
+
+	// IsStrict returns whether references to undefined variables should fail
+	// evaluation, instead of rendering as "<no value>".
+	IsStrict() bool
+
+	// GetDelimiters returns the delimiters of template actions in rendered files
+	// and their names, unless overridden by .jendelims files or directives. They
+	// do not apply to expressions of the spec file itself.
+	GetDelimiters() evaluation.Delimiters
+
+	// GetOverwritePolicy returns the overwrite policy forced via command line for
+	// all render steps, or evaluation.DefaultOverwrite to let steps decide.
+	GetOverwritePolicy() evaluation.OverwritePolicy
 }
 
 // Executable represents an entity that can perform some work
@@ -60,7 +115,7 @@ type Executables []Executable
 func (executables Executables) Execute(context Context) error {
 	for _, e := range executables {
 		if err := e.Execute(context); err != nil {
-			return err
+			return evaluation.AddToChain(err, fmt.Sprint(e))
 		}
 	}
 	return nil
@@ -1,23 +1,72 @@
 package conversion
 
 import (
+	"encoding/json"
 	"fmt"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
 )
 
-// ToString converts an abstract value (can be either string or bool) to its string representation
-func ToString(value interface{}) (string, error) {
-	strValue, ok := value.(string)
-	if ok {
-		return strValue, nil
-	}
+// Type represents the type of a variable's value
+type Type string
 
-	boolValue, ok := value.(bool)
-	if ok {
-		return strconv.FormatBool(boolValue), nil
+// Supported variable types
+const (
+	String Type = "string"
+	Bool   Type = "bool"
+	Int    Type = "int"
+	Float  Type = "float"
+	List   Type = "list"
+	Map    Type = "map"
+)
+
+// TypeOf returns the type of given abstract value, defaulting to String for unsupported types
+func TypeOf(value interface{}) Type {
+	switch Normalize(value).(type) {
+	case bool:
+		return Bool
+	case int, int64, uint64:
+		return Int
+	case float64:
+		return Float
+	case []interface{}:
+		return List
+	case map[string]interface{}:
+		return Map
+	default:
+		return String
 	}
+}
 
-	return "", fmt.Errorf("failed to convert type into string: %t", value)
+// ToString converts an abstract value (can be either string, bool, number, list or map) to its string
+// representation, using json format for lists and maps
+func ToString(value interface{}) (string, error) {
+	switch v := Normalize(value).(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []interface{}, map[string]interface{}:
+		buf, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("failed to convert value into string: %w", err)
+		}
+		return string(buf), nil
+	default:
+		return "", fmt.Errorf("failed to convert type into string: %T", value)
+	}
 }
 
 // ToBool converts an abstract value (can be either string or bool) to its string representation
@@ -34,3 +83,114 @@ func ToBool(value interface{}) (bool, error) {
 
 	return false, fmt.Errorf("failed to convert type into bool: %t", value)
 }
+
+// Parse converts given text into a value of given type. Lists and maps can be expressed in either json
+// or yaml flow format (ie: `[a, b]` or `{a: 1}`).
+func Parse(text string, typ Type) (interface{}, error) {
+	switch typ {
+	case String, "":
+		return text, nil
+	case Bool:
+		value, err := strconv.ParseBool(strings.TrimSpace(text))
+		if err != nil {
+			return nil, fmt.Errorf("invalid bool value %q", text)
+		}
+		return value, nil
+	case Int:
+		value, err := strconv.Atoi(strings.TrimSpace(text))
+		if err != nil {
+			return nil, fmt.Errorf("invalid int value %q", text)
+		}
+		return value, nil
+	case Float:
+		value, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float value %q", text)
+		}
+		return value, nil
+	case List:
+		var value []interface{}
+		if err := yaml.Unmarshal([]byte(text), &value); err != nil {
+			return nil, fmt.Errorf("invalid list value %q", text)
+		}
+		if value == nil {
+			value = []interface{}{}
+		}
+		return Normalize(value), nil
+	case Map:
+		var value map[interface{}]interface{}
+		if err := yaml.Unmarshal([]byte(text), &value); err != nil {
+			return nil, fmt.Errorf("invalid map value %q", text)
+		}
+		if value == nil {
+			return map[string]interface{}{}, nil
+		}
+		return Normalize(value), nil
+	default:
+		return nil, fmt.Errorf("unsupported type %q", typ)
+	}
+}
+
+// Normalize recursively converts the maps decoded from yaml, whose keys are of any type, into maps with
+// string keys, as decoded from json, for consistency within templates and json encoding
+func Normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = Normalize(item)
+		}
+		return m
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = Normalize(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = Normalize(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+var envNameRegexp = regexp.MustCompile(`\W+`)
+
+// ToEnvVars flattens given variable into "NAME=value" env var entries. Lists and maps are exposed as json
+// in the variable itself, with each of their items also exposed as a separate variable suffixed with its
+// index or upper-cased key (ie: "VAR_0" or "VAR_KEY"), recursively.
+func ToEnvVars(name string, value interface{}) ([]string, error) {
+	str, err := ToString(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert variable %q: %w", name, err)
+	}
+	entries := []string{fmt.Sprintf("%s=%s", name, str)}
+
+	switch v := Normalize(value).(type) {
+	case []interface{}:
+		for i, item := range v {
+			children, err := ToEnvVars(fmt.Sprintf("%s_%d", name, i), item)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, children...)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			suffix := strings.ToUpper(envNameRegexp.ReplaceAllString(key, "_"))
+			children, err := ToEnvVars(name+"_"+suffix, v[key])
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, children...)
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,85 @@
+package conversion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type varMap = map[string]interface{}
+
+func TestToString(t *testing.T) {
+	fixtures := []struct {
+		Name     string
+		Value    interface{}
+		Expected string
+	}{
+		{Name: "string", Value: "abc", Expected: "abc"},
+		{Name: "bool", Value: true, Expected: "true"},
+		{Name: "int", Value: 8080, Expected: "8080"},
+		{Name: "float", Value: 0.5, Expected: "0.5"},
+		{Name: "list", Value: []interface{}{"a", 1}, Expected: `["a",1]`},
+		{Name: "yaml map", Value: map[interface{}]interface{}{"b": 2, "a": []interface{}{true}}, Expected: `{"a":[true],"b":2}`},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			actual, err := ToString(f.Value)
+			assert.NoError(t, err)
+			assert.Equal(t, f.Expected, actual)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	fixtures := []struct {
+		Name     string
+		Text     string
+		Type     Type
+		Expected interface{}
+		Error    string
+	}{
+		{Name: "string", Text: " abc ", Type: String, Expected: " abc "},
+		{Name: "bool", Text: "true", Type: Bool, Expected: true},
+		{Name: "int", Text: " 8080", Type: Int, Expected: 8080},
+		{Name: "float", Text: "0.5", Type: Float, Expected: 0.5},
+		{Name: "json list", Text: `["a", 1]`, Type: List, Expected: []interface{}{"a", 1}},
+		{Name: "yaml list", Text: `[a, b]`, Type: List, Expected: []interface{}{"a", "b"}},
+		{Name: "empty list", Text: ``, Type: List, Expected: []interface{}{}},
+		{Name: "map", Text: `{host: localhost, ports: [80]}`, Type: Map, Expected: varMap{"host": "localhost", "ports": []interface{}{80}}},
+		{Name: "invalid int", Text: "abc", Type: Int, Error: `invalid int value "abc"`},
+		{Name: "invalid list", Text: "{a: 1}", Type: List, Error: `invalid list value "{a: 1}"`},
+		{Name: "invalid type", Text: "abc", Type: "date", Error: `unsupported type "date"`},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			actual, err := Parse(f.Text, f.Type)
+
+			if f.Error != "" {
+				assert.EqualError(t, err, f.Error)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, f.Expected, actual)
+				assert.Equal(t, f.Type, TypeOf(actual))
+			}
+		})
+	}
+}
+
+func TestToEnvVars(t *testing.T) {
+	value := varMap{
+		"hosts":     []interface{}{"a.com", "b.com"},
+		"max-conns": 10,
+	}
+
+	actual, err := ToEnvVars("DB", value)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		`DB={"hosts":["a.com","b.com"],"max-conns":10}`,
+		`DB_HOSTS=["a.com","b.com"]`,
+		`DB_HOSTS_0=a.com`,
+		`DB_HOSTS_1=b.com`,
+		`DB_MAX_CONNS=10`,
+	}, actual)
+}
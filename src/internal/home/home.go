@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/Samasource/jen/src/internal/constant"
 	"github.com/Samasource/jen/src/internal/helpers"
@@ -21,6 +22,10 @@ const (
 	// jenRepoVar is the name of env var specifying the URL of jen git repo containing user scripts and templates.
 	jenRepoVar = "JEN_REPO"
 
+	// jenSecretsVar is the name of env var specifying the path of the file where values of secret variables are
+	// saved.
+	jenSecretsVar = "JEN_SECRETS"
+
 	// jenRepoSubDirVar is the name of env var specifying the sub-directory within jen git repo, where user shared scripts
 	// "bin" and "templates" directories are located.
 	jenRepoSubDirVar = "JEN_SUBDIR"
@@ -91,6 +96,21 @@ func getCloneDir() (cloneDir string, err error) {
 	return
 }
 
+// GetSecretsFile returns the path of the file where values of secret variables are saved, as specified by
+// JEN_SECRETS env var, defaulting to "~/.jen/secrets.yaml".
+func GetSecretsFile() (string, error) {
+	path, ok := os.LookupEnv(jenSecretsVar)
+	if ok && path != "" {
+		return path, nil
+	}
+
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", fmt.Errorf("failed to detect home directory: %w", err)
+	}
+	return filepath.Join(home, constant.DefaultSecretsFile), nil
+}
+
 // GetCloneSubDir returns the path within cloned git repo where to look
 // for "bin" and "templates" directories.
 func GetCloneSubDir() (string, error) {
@@ -112,3 +132,36 @@ func GetTemplatesDir() (string, error) {
 
 	return filepath.Join(repoSubDir, constant.TemplatesDirName), nil
 }
+
+// GetRepoCommit returns the hash of the commit currently checked out in the templates git repo clone
+func GetRepoCommit() (string, error) {
+	cloneDir, err := getCloneDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate clone dir: %w", err)
+	}
+	output, err := shell.ExecuteOutput(nil, cloneDir, "git rev-parse HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to determine current commit of templates repo: %w", err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// ExportTemplateDir extracts the content of the templates git repo at given commit into given dir, and returns
+// the path where the given template dir (as found in current clone) is located within that extracted copy.
+func ExportTemplateDir(commit, templateDir, dir string) (string, error) {
+	cloneDir, err := getCloneDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate clone dir: %w", err)
+	}
+	relativeDir, err := filepath.Rel(cloneDir, templateDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to locate template dir %q within clone dir %q: %w", templateDir, cloneDir, err)
+	}
+
+	logging.Log("Exporting templates repo at commit %q into dir %q", commit, dir)
+	err = shell.Execute(nil, cloneDir, fmt.Sprintf("git archive --format=tar %q | tar -x -C %q", commit, dir))
+	if err != nil {
+		return "", fmt.Errorf("failed to export templates repo at commit %q: %w", commit, err)
+	}
+	return filepath.Join(dir, relativeDir), nil
+}
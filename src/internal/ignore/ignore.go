@@ -0,0 +1,176 @@
+package ignore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Samasource/jen/src/internal/vfs"
+)
+
+// Matcher represents a set of gitignore-syntax patterns relative to a given base dir
+type Matcher struct {
+	baseDir  string
+	patterns []pattern
+}
+
+type pattern struct {
+	text    string
+	regex   *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// New creates a matcher from given gitignore-syntax lines, with patterns relative to given base dir
+func New(baseDir string, lines []string) (*Matcher, error) {
+	matcher := &Matcher{baseDir: baseDir}
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p, err := parsePattern(line)
+		if err != nil {
+			return nil, err
+		}
+		matcher.patterns = append(matcher.patterns, p)
+	}
+	return matcher, nil
+}
+
+// Load creates a matcher from the gitignore-syntax file at given path of given file system, with patterns relative
+// to the file's own dir. It returns nil if the file does not exist.
+func Load(fsys vfs.Reader, path string) (*Matcher, error) {
+	buf, err := fsys.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read ignore file %q: %w", path, err)
+	}
+	matcher, err := New(filepath.Dir(path), strings.Split(string(buf), "\n"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ignore file %q: %w", path, err)
+	}
+	return matcher, nil
+}
+
+func parsePattern(line string) (pattern, error) {
+	p := pattern{text: line}
+
+	// Negation
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	// Directory-only
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+
+	// Patterns containing a slash are anchored to base dir, others match at any level
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	expr := globToRegexp(line)
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	regex, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return pattern{}, fmt.Errorf("invalid ignore pattern %q: %w", p.text, err)
+	}
+	p.regex = regex
+	return p, nil
+}
+
+// globToRegexp converts a gitignore glob, including "**" wildcards, into an equivalent regular expression
+func globToRegexp(glob string) string {
+	var builder strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			builder.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			builder.WriteString(".*")
+			i++
+		case c == '*':
+			builder.WriteString("[^/]*")
+		case c == '?':
+			builder.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end == -1 {
+				builder.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			builder.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			builder.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return builder.String()
+}
+
+// match determines whether given path (absolute or relative to current dir) is matched by any of this matcher's
+// patterns, returning whether it is ignored and whether any pattern applied at all
+func (m *Matcher) match(path string, isDir bool) (ignored bool, matched bool) {
+	rel, err := filepath.Rel(m.baseDir, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false, false
+	}
+	rel = filepath.ToSlash(rel)
+
+	// Last matching pattern wins
+	for _, p := range m.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.regex.MatchString(rel) {
+			ignored = !p.negate
+			matched = true
+		}
+	}
+	return ignored, matched
+}
+
+// Stack represents a hierarchy of matchers, from the root dir down to the current dir, where deeper matchers take
+// precedence over shallower ones
+type Stack []*Matcher
+
+// Push returns a new stack with given matcher appended, or the same stack if matcher is nil
+func (s Stack) Push(matcher *Matcher) Stack {
+	if matcher == nil {
+		return s
+	}
+	stack := make(Stack, len(s), len(s)+1)
+	copy(stack, s)
+	return append(stack, matcher)
+}
+
+// IsIgnored determines whether given file or dir path is ignored by any matcher in stack
+func (s Stack) IsIgnored(path string, isDir bool) bool {
+	ignored := false
+	for _, matcher := range s {
+		if value, ok := matcher.match(path, isDir); ok {
+			ignored = value
+		}
+	}
+	return ignored
+}
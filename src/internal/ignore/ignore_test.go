@@ -0,0 +1,58 @@
+package ignore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsIgnored(t *testing.T) {
+	root, err := New("/template", []string{
+		"# Comment",
+		"",
+		".DS_Store",
+		"*.swp",
+		"/README.md",
+		"fixtures/",
+		"docs/**/*.draft",
+		"*.log",
+		"!important.log",
+	})
+	assert.NoError(t, err)
+	child, err := New("/template/sub", []string{
+		"local.txt",
+		"!keep.swp",
+	})
+	assert.NoError(t, err)
+	stack := Stack{}.Push(root).Push(child)
+
+	fixtures := []struct {
+		Path     string
+		IsDir    bool
+		Expected bool
+	}{
+		{Path: "/template/.DS_Store", Expected: true},
+		{Path: "/template/a/b/.DS_Store", Expected: true},
+		{Path: "/template/file.txt.swp", Expected: true},
+		{Path: "/template/README.md", Expected: true},
+		{Path: "/template/sub/README.md", Expected: false},
+		{Path: "/template/fixtures", IsDir: true, Expected: true},
+		{Path: "/template/a/fixtures", IsDir: true, Expected: true},
+		{Path: "/template/fixtures", IsDir: false, Expected: false},
+		{Path: "/template/docs/guide.draft", Expected: true},
+		{Path: "/template/docs/a/b/guide.draft", Expected: true},
+		{Path: "/template/other/guide.draft", Expected: false},
+		{Path: "/template/debug.log", Expected: true},
+		{Path: "/template/important.log", Expected: false},
+		{Path: "/template/sub/local.txt", Expected: true},
+		{Path: "/template/local.txt", Expected: false},
+		{Path: "/template/sub/keep.swp", Expected: false},
+		{Path: "/template/main.go", Expected: false},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.Path, func(t *testing.T) {
+			assert.Equal(t, f.Expected, stack.IsIgnored(f.Path, f.IsDir))
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package logging
 
-import "fmt"
+import (
+	"fmt"
+	"os"
+)
 
 var (
 	Verbose bool
@@ -12,3 +15,17 @@ func Log(message string, a ...interface{}) {
 		fmt.Println()
 	}
 }
+
+// Plan displays a message describing an operation that would be performed
+// if we were not in dry-run mode. It is displayed regardless of verbosity.
+func Plan(message string, a ...interface{}) {
+	fmt.Printf("[dry-run] "+message, a...)
+	fmt.Println()
+}
+
+// Warning displays a message about a potential problem that does not prevent
+// the operation from completing. It is displayed regardless of verbosity.
+func Warning(message string, a ...interface{}) {
+	fmt.Fprintf(os.Stderr, "WARNING: "+message, a...)
+	fmt.Fprintln(os.Stderr)
+}
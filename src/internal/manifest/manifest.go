@@ -0,0 +1,153 @@
+package manifest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/Samasource/jen/src/internal/constant"
+	"github.com/Samasource/jen/src/internal/helpers"
+	"gopkg.in/yaml.v2"
+)
+
+// Manifest records how a project's files were rendered from its template, so that
+// they can later be upgraded to a newer revision of that template
+type Manifest struct {
+	Version string
+
+	// Renders lists the distinct render steps that were executed
+	Renders []Render
+
+	// Files maps paths of rendered files, relative to project dir, to their origin
+	Files map[string]File
+}
+
+// Render represents a render step's source dir (relative to template dir) and target
+// dir (relative to project dir), as last executed from a given templates git repo commit
+type Render struct {
+	Source string
+	Target string
+	Commit string
+
+	// Ignore lists the step's own ignore patterns, in addition to those of .jenignore files
+	Ignore []string `yaml:",omitempty"`
+
+	// Foreach is the step's expression resulting in the list of items to render source dir for, if any
+	Foreach string `yaml:",omitempty"`
+
+	// As is the name of the variable holding current item when looping with Foreach, if specified
+	As string `yaml:",omitempty"`
+
+	// Overwrite is the step's own policy for existing files, if specified
+	Overwrite string `yaml:",omitempty"`
+
+	// Modes maps the step's glob patterns to octal file modes (ie: "0755")
+	Modes map[string]string `yaml:",omitempty"`
+
+	// Binary lists the step's glob patterns of files to copy verbatim, in addition to those detected as binary
+	Binary []string `yaml:",omitempty"`
+}
+
+// FormatModes converts given file modes into their octal representation, for recording them
+func FormatModes(modes map[string]os.FileMode) map[string]string {
+	if len(modes) == 0 {
+		return nil
+	}
+	formatted := make(map[string]string, len(modes))
+	for pattern, mode := range modes {
+		formatted[pattern] = fmt.Sprintf("%04o", mode)
+	}
+	return formatted
+}
+
+// GetModes returns the recorded file modes of render step
+func (r Render) GetModes() (map[string]os.FileMode, error) {
+	if len(r.Modes) == 0 {
+		return nil, nil
+	}
+	modes := make(map[string]os.FileMode, len(r.Modes))
+	for pattern, str := range r.Modes {
+		mode, err := strconv.ParseUint(str, 8, 32)
+		if err != nil || mode > 0777 {
+			return nil, fmt.Errorf("invalid octal mode %q for pattern %q in %s", str, pattern, constant.ManifestFileName)
+		}
+		modes[pattern] = os.FileMode(mode)
+	}
+	return modes, nil
+}
+
+// File represents the origin of a rendered file
+type File struct {
+	// Source is the path of template file, relative to template dir
+	Source string
+
+	// Hash is the SHA-256 hex digest of file content as rendered from template
+	Hash string
+}
+
+// Load loads the manifest file from given project directory, returning an empty
+// manifest if it does not exist yet
+func Load(projectDir string) (*Manifest, error) {
+	path := filepath.Join(projectDir, constant.ManifestFileName)
+	if !helpers.PathExists(path) {
+		return &Manifest{
+			Files: make(map[string]File),
+		}, nil
+	}
+
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("loading manifest file: %w", err)
+	}
+	var manifest Manifest
+	err = yaml.Unmarshal(buf, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshalling manifest file yaml: %w", err)
+	}
+	if manifest.Version != constant.ManifestFileVersion {
+		return nil, fmt.Errorf("unsupported jen manifest file version %s (expected %s)", manifest.Version, constant.ManifestFileVersion)
+	}
+	if manifest.Files == nil {
+		manifest.Files = make(map[string]File)
+	}
+	return &manifest, nil
+}
+
+// Save saves manifest file into given project directory
+func (m Manifest) Save(projectDir string) error {
+	m.Version = constant.ManifestFileVersion
+	doc, err := yaml.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(projectDir, constant.ManifestFileName)
+	return ioutil.WriteFile(path, doc, 0644)
+}
+
+// SetRender records given render step as last executed, replacing any previous record of a render step with
+// same source and target dirs
+func (m *Manifest) SetRender(render Render) {
+	render.Source = filepath.Clean(render.Source)
+	render.Target = filepath.Clean(render.Target)
+	for i, r := range m.Renders {
+		if r.Source == render.Source && r.Target == render.Target {
+			m.Renders[i] = render
+			return
+		}
+	}
+	m.Renders = append(m.Renders, render)
+}
+
+// GetPaths returns the sorted paths of all rendered files
+func (m Manifest) GetPaths() []string {
+	paths := make([]string, 0, len(m.Files))
+	for path := range m.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
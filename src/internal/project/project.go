@@ -5,13 +5,17 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"strings"
 
 	survey "github.com/AlecAivazis/survey/v2"
 	"github.com/Samasource/jen/src/internal/constant"
 	"github.com/Samasource/jen/src/internal/helpers"
+	"github.com/Samasource/jen/src/internal/helpers/conversion"
 	"github.com/Samasource/jen/src/internal/home"
+	"github.com/Samasource/jen/src/internal/logging"
+	"github.com/Samasource/jen/src/internal/schema"
 	"github.com/Samasource/jen/src/internal/spec"
 	"gopkg.in/yaml.v2"
 )
@@ -39,15 +43,80 @@ func GetProjectDir() (string, error) {
 
 // Project represents the configuration file in a project's root dir
 type Project struct {
-	Version       string
-	TemplateName  string
-	Vars          map[string]interface{}
+	Version      string
+	TemplateName string
+	Vars         map[string]interface{}
+
+	// Profiles maps names of profiles (ie: "staging" or "prod") to variables
+	// overlaying base ones when profile is active
+	Profiles map[string]map[string]interface{} `yaml:",omitempty"`
+
+	// Profile is the name of active profile, if any
+	Profile string `yaml:"-"`
+
 	Dir           string   `yaml:"-"`
 	OverridenVars []string `yaml:"-"`
+	DryRun        bool     `yaml:"-"`
+}
+
+// GetVars returns a copy of base variables overlaid with those of active
+// profile, if any
+func (p Project) GetVars() map[string]interface{} {
+	vars := make(map[string]interface{})
+	for k, v := range p.Vars {
+		vars[k] = v
+	}
+	for k, v := range p.Profiles[p.Profile] {
+		vars[k] = v
+	}
+	return vars
+}
+
+// IsProfileVar returns whether given variable is defined by active profile
+func (p Project) IsProfileVar(name string) bool {
+	_, ok := p.Profiles[p.Profile][name]
+	return ok
 }
 
-// Save saves project file into given project directory
+// SetVars updates variables, as returned by GetVars() and then modified, and
+// saves project file. When a profile is active, modified variables are written
+// to that profile and removed variables are only removed from that profile,
+// leaving base variables untouched.
+func (p *Project) SetVars(vars map[string]interface{}) error {
+	if p.Profile == "" {
+		p.Vars = vars
+		return p.Save()
+	}
+
+	current := p.GetVars()
+	profile := p.Profiles[p.Profile]
+	if profile == nil {
+		profile = make(map[string]interface{})
+	}
+	for name, value := range vars {
+		if existing, ok := current[name]; !ok || !reflect.DeepEqual(existing, value) {
+			profile[name] = value
+		}
+	}
+	for name := range current {
+		if _, ok := vars[name]; !ok {
+			delete(profile, name)
+		}
+	}
+	if p.Profiles == nil {
+		p.Profiles = make(map[string]map[string]interface{})
+	}
+	p.Profiles[p.Profile] = profile
+	return p.Save()
+}
+
+// Save saves project file into given project directory, unless in dry-run mode,
+// in which case changes are only kept in memory
 func (p Project) Save() error {
+	if p.DryRun {
+		logging.Log("Skipping save of project file in dry-run mode")
+		return nil
+	}
 	p.Version = constant.ProjectFileVersion
 	doc, err := yaml.Marshal(p)
 	if err != nil {
@@ -75,6 +144,17 @@ func Load(dir string) (*Project, error) {
 		return nil, fmt.Errorf("unsupported jen project file version %s (expected %s)", project.Version, constant.ProjectFileVersion)
 	}
 
+	if project.Vars == nil {
+		project.Vars = make(map[string]interface{})
+	}
+	for name, value := range project.Vars {
+		project.Vars[name] = conversion.Normalize(value)
+	}
+	for _, vars := range project.Profiles {
+		for name, value := range vars {
+			vars[name] = conversion.Normalize(value)
+		}
+	}
 	project.Dir = dir
 	return &project, nil
 }
@@ -83,11 +163,12 @@ var varOverrideRegexp = regexp.MustCompile(`^(\w+)=(.*)$`)
 
 // LoadOrCreate loads current project file and, if it doesn't
 // exists, prompts user whether to create it.
-func LoadOrCreate(templateName string, skipConfirm bool, varOverrides []string) (*Project, error) {
+func LoadOrCreate(templateName string, skipConfirm, dryRun bool) (*Project, error) {
 	projectDir, err := GetProjectDir()
 	if err != nil {
 		return nil, err
 	}
+	var proj *Project
 	if projectDir == "" {
 		if !skipConfirm {
 			err := confirmCreateProject()
@@ -95,15 +176,22 @@ func LoadOrCreate(templateName string, skipConfirm bool, varOverrides []string)
 				return nil, err
 			}
 		}
-		proj := Project{}
+		if dryRun {
+			logging.Plan("Would create project file %q in current directory", constant.ProjectFileName)
+		}
+		proj = &Project{
+			Vars:   make(map[string]interface{}),
+			DryRun: dryRun,
+		}
 		if err := proj.Save(); err != nil {
 			return nil, err
 		}
-	}
-
-	proj, err := Load(projectDir)
-	if err != nil {
-		return nil, err
+	} else {
+		proj, err = Load(projectDir)
+		if err != nil {
+			return nil, err
+		}
+		proj.DryRun = dryRun
 	}
 
 	if templateName != "" {
@@ -127,24 +215,44 @@ func LoadOrCreate(templateName string, skipConfirm bool, varOverrides []string)
 		}
 	}
 
-	// Apply command-line variable overrides
+	return proj, nil
+}
+
+// ApplyOverrides sets variables passed via command line, as "NAME=value" entries, and saves project file.
+// Values of variables declared in given schema are converted to their declared type and validated, while
+// other values preserve the type of existing values, if any. When a profile is active, variables are set
+// in that profile. Values of secret variables are never set in project file, but returned instead, for
+// caller to save them in secrets file.
+func (p *Project) ApplyOverrides(varOverrides []string, vars schema.Schema) (map[string]string, error) {
+	if len(varOverrides) == 0 {
+		return nil, nil
+	}
+	projectVars := p.GetVars()
+	secretVars := make(map[string]string)
 	for _, entry := range varOverrides {
 		submatch := varOverrideRegexp.FindStringSubmatch(entry)
 		if submatch == nil {
 			return nil, fmt.Errorf("failed to parse set variable %q", entry)
 		}
 		name := submatch[1]
-		value := submatch[2]
-		proj.Vars[name] = value
-		proj.OverridenVars = append(proj.OverridenVars, name)
-	}
-	if len(varOverrides) > 0 {
-		if err := proj.Save(); err != nil {
-			return nil, err
+		p.OverridenVars = append(p.OverridenVars, name)
+		if vars[name].Secret {
+			secretVars[name] = submatch[2]
+			continue
 		}
+		var value interface{} = submatch[2]
+		var err error
+		if v, ok := vars[name]; ok {
+			value, err = v.Parse(submatch[2])
+		} else if existing, ok := projectVars[name]; ok {
+			value, err = conversion.Parse(submatch[2], conversion.TypeOf(existing))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse set variable %q: %w", entry, err)
+		}
+		projectVars[name] = value
 	}
-
-	return proj, nil
+	return secretVars, p.SetVars(projectVars)
 }
 
 func confirmCreateProject() error {
@@ -4,6 +4,8 @@ import (
 	"io/ioutil"
 	"testing"
 
+	"github.com/Samasource/jen/src/internal/helpers/conversion"
+	"github.com/Samasource/jen/src/internal/schema"
 	"github.com/go-test/deep"
 	"github.com/stretchr/testify/assert"
 )
@@ -17,6 +19,13 @@ func TestSaveAndLoad(t *testing.T) {
 		"TRUE_VAR":  true,
 		"FALSE_VAR": false,
 		"STR_VAR":   "abc",
+		"INT_VAR":   8080,
+		"FLOAT_VAR": 0.5,
+		"LIST_VAR":  []interface{}{"a", 1, true},
+		"MAP_VAR": varMap{
+			"host":  "localhost",
+			"ports": []interface{}{80, 443},
+		},
 	}}
 	proj.Dir = getTempDir()
 	err := proj.Save()
@@ -32,6 +41,101 @@ func TestSaveAndLoad(t *testing.T) {
 	}
 }
 
+func TestApplyOverrides(t *testing.T) {
+	vars := schema.Schema{
+		"REPLICAS": schema.Var{Name: "REPLICAS", Type: conversion.Int},
+		"ENV":      schema.Var{Name: "ENV", Type: conversion.String, Enum: []interface{}{"dev", "prod"}},
+		"TOKEN":    schema.Var{Name: "TOKEN", Type: conversion.String, Secret: true},
+	}
+	proj := Project{
+		Vars: varMap{
+			"HOSTS": []interface{}{"a.com"},
+		},
+		Dir: getTempDir(),
+	}
+
+	secretVars, err := proj.ApplyOverrides([]string{"REPLICAS=3", "HOSTS=[b.com, c.com]", "NAME=abc", "TOKEN=s3cr3t"}, vars)
+	assert.NoError(t, err)
+	assert.Equal(t, varMap{
+		"REPLICAS": 3,
+		"HOSTS":    []interface{}{"b.com", "c.com"},
+		"NAME":     "abc",
+	}, proj.Vars)
+	assert.Equal(t, map[string]string{"TOKEN": "s3cr3t"}, secretVars)
+	assert.Equal(t, []string{"REPLICAS", "HOSTS", "NAME", "TOKEN"}, proj.OverridenVars)
+
+	_, err = proj.ApplyOverrides([]string{"ENV=staging"}, vars)
+	assert.EqualError(t, err, `failed to parse set variable "ENV=staging": value "staging" must be one of: dev, prod`)
+
+	_, err = proj.ApplyOverrides([]string{"REPLICAS=many"}, vars)
+	assert.EqualError(t, err, `failed to parse set variable "REPLICAS=many": invalid int value "many"`)
+}
+
+func TestProfiles(t *testing.T) {
+	proj := Project{
+		Vars: varMap{
+			"PROJECT":  "app",
+			"CLUSTER":  "dev-cluster",
+			"REPLICAS": 1,
+		},
+		Profiles: map[string]map[string]interface{}{
+			"prod": {
+				"CLUSTER":  "prod-cluster",
+				"REPLICAS": 3,
+			},
+		},
+		Dir: getTempDir(),
+	}
+
+	// Without profile
+	assert.Equal(t, proj.Vars, proj.GetVars())
+	assert.False(t, proj.IsProfileVar("CLUSTER"))
+
+	// With profile
+	proj.Profile = "prod"
+	vars := proj.GetVars()
+	assert.Equal(t, varMap{
+		"PROJECT":  "app",
+		"CLUSTER":  "prod-cluster",
+		"REPLICAS": 3,
+	}, vars)
+	assert.True(t, proj.IsProfileVar("CLUSTER"))
+	assert.False(t, proj.IsProfileVar("PROJECT"))
+
+	// Modified and new variables are written to profile only, while removed
+	// variables are only removed from profile
+	vars["REGION"] = "us-east-1"
+	vars["REPLICAS"] = 5
+	delete(vars, "CLUSTER")
+	delete(vars, "PROJECT")
+	assert.NoError(t, proj.SetVars(vars))
+	assert.Equal(t, varMap{
+		"PROJECT":  "app",
+		"CLUSTER":  "dev-cluster",
+		"REPLICAS": 1,
+	}, proj.Vars)
+	assert.Equal(t, varMap{
+		"REGION":   "us-east-1",
+		"REPLICAS": 5,
+	}, proj.Profiles["prod"])
+
+	// Overrides are also written to profile, which gets created as needed
+	proj.Profile = "staging"
+	_, err := proj.ApplyOverrides([]string{"REPLICAS=2", "PROJECT=app"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, varMap{"REPLICAS": 2}, proj.Profiles["staging"])
+
+	// Save and load
+	actualProj, err := Load(proj.Dir)
+	assert.NoError(t, err)
+	if diff := deep.Equal(proj.Vars, actualProj.Vars); diff != nil {
+		t.Error(diff)
+	}
+	if diff := deep.Equal(proj.Profiles, actualProj.Profiles); diff != nil {
+		t.Error(diff)
+	}
+}
+
 func getTempDir() string {
 	dir, err := ioutil.TempDir("/tmp", "jen_test_")
 	if err != nil {
@@ -0,0 +1,152 @@
+package schema
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Samasource/jen/src/internal/helpers/conversion"
+)
+
+// Var describes a project variable, as declared in template spec
+type Var struct {
+	Name        string
+	Type        conversion.Type
+	Description string
+
+	// Default is the value of variable when it is not defined in project file, or nil for no default
+	Default interface{}
+
+	// Required determines whether variable must be defined for `jen require` to succeed
+	Required bool
+
+	// Enum lists the only values allowed for variable, if any
+	Enum []interface{}
+
+	// Regex is the pattern that the string representation of values must match, if any
+	Regex *regexp.Regexp
+
+	// Secret determines whether variable is prompted for by a secret input step, so that its value must be saved
+	// in per-user secrets file, rather than in project file
+	Secret bool
+}
+
+// Parse converts given text into a value of variable's type and validates it
+func (v Var) Parse(text string) (interface{}, error) {
+	value, err := conversion.Parse(text, v.Type)
+	if err != nil {
+		return nil, err
+	}
+	return v.Coerce(value)
+}
+
+// Coerce converts given value into variable's type, if it is a string representation of such a value
+// (ie: "123" for an int variable), validates it and returns it
+func (v Var) Coerce(value interface{}) (interface{}, error) {
+	actualType := conversion.TypeOf(value)
+	switch {
+	case actualType == v.Type:
+	case actualType == conversion.String:
+		var err error
+		value, err = conversion.Parse(value.(string), v.Type)
+		if err != nil {
+			return nil, err
+		}
+	case actualType == conversion.Int && v.Type == conversion.Float:
+		str, _ := conversion.ToString(value)
+		value, _ = conversion.Parse(str, conversion.Float)
+	default:
+		return nil, fmt.Errorf("expected %s value, not %s", v.Type, actualType)
+	}
+
+	str, err := conversion.ToString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(v.Enum) > 0 && !v.isInEnum(str) {
+		return nil, fmt.Errorf("value %q must be one of: %s", str, v.enumString())
+	}
+	if v.Regex != nil && !v.Regex.MatchString(str) {
+		return nil, fmt.Errorf("value %q must match pattern %q", str, v.Regex)
+	}
+	return value, nil
+}
+
+// isInEnum returns whether given string representation of a value matches any of allowed values
+func (v Var) isInEnum(str string) bool {
+	for _, item := range v.Enum {
+		if itemStr, _ := conversion.ToString(item); itemStr == str {
+			return true
+		}
+	}
+	return false
+}
+
+// enumString returns the comma-separated list of allowed values
+func (v Var) enumString() string {
+	items := make([]string, len(v.Enum))
+	for i, item := range v.Enum {
+		items[i], _ = conversion.ToString(item)
+	}
+	return strings.Join(items, ", ")
+}
+
+// Schema maps names of project variables declared in template spec to their definitions
+type Schema map[string]Var
+
+// Names returns the sorted names of declared variables
+func (s Schema) Names() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyDefaults sets all declared variables that are missing from given vars to their default value
+func (s Schema) ApplyDefaults(vars map[string]interface{}) {
+	for name, v := range s {
+		if _, ok := vars[name]; !ok && v.Default != nil {
+			vars[name] = v.Default
+		}
+	}
+}
+
+// Validate converts all declared variables found in given vars to their declared types, in place, and
+// returns an error listing all those that do not conform to their definition
+func (s Schema) Validate(vars map[string]interface{}) error {
+	var messages []string
+	for _, name := range s.Names() {
+		value, ok := vars[name]
+		if !ok {
+			continue
+		}
+		value, err := s[name].Coerce(value)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("invalid variable %q: %v", name, err))
+			continue
+		}
+		vars[name] = value
+	}
+	switch len(messages) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("%s", messages[0])
+	default:
+		return fmt.Errorf("found %d invalid variables:\n%s", len(messages), strings.Join(messages, "\n"))
+	}
+}
+
+// GetMissing returns the sorted names of required variables missing from given vars
+func (s Schema) GetMissing(vars map[string]interface{}) []string {
+	var missing []string
+	for _, name := range s.Names() {
+		if _, ok := vars[name]; !ok && s[name].Required {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,123 @@
+package schema
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/Samasource/jen/src/internal/helpers/conversion"
+	"github.com/stretchr/testify/assert"
+)
+
+type varMap = map[string]interface{}
+
+func TestVarCoerce(t *testing.T) {
+	fixtures := []struct {
+		Name     string
+		Var      Var
+		Value    interface{}
+		Expected interface{}
+		Error    string
+	}{
+		{
+			Name:     "string",
+			Var:      Var{Type: conversion.String},
+			Value:    "abc",
+			Expected: "abc",
+		},
+		{
+			Name:     "int from string",
+			Var:      Var{Type: conversion.Int},
+			Value:    "8080",
+			Expected: 8080,
+		},
+		{
+			Name:     "float from int",
+			Var:      Var{Type: conversion.Float},
+			Value:    2,
+			Expected: 2.0,
+		},
+		{
+			Name:     "bool from string",
+			Var:      Var{Type: conversion.Bool},
+			Value:    "true",
+			Expected: true,
+		},
+		{
+			Name:     "list from json",
+			Var:      Var{Type: conversion.List},
+			Value:    `["a", "b"]`,
+			Expected: []interface{}{"a", "b"},
+		},
+		{
+			Name:  "mismatching type",
+			Var:   Var{Type: conversion.String},
+			Value: 123,
+			Error: "expected string value, not int",
+		},
+		{
+			Name:  "invalid int",
+			Var:   Var{Type: conversion.Int},
+			Value: "abc",
+			Error: `invalid int value "abc"`,
+		},
+		{
+			Name:     "enum",
+			Var:      Var{Type: conversion.String, Enum: []interface{}{"dev", "prod"}},
+			Value:    "prod",
+			Expected: "prod",
+		},
+		{
+			Name:  "not in enum",
+			Var:   Var{Type: conversion.Int, Enum: []interface{}{1, 3}},
+			Value: 2,
+			Error: `value "2" must be one of: 1, 3`,
+		},
+		{
+			Name:     "regex",
+			Var:      Var{Type: conversion.String, Regex: regexp.MustCompile(`^[a-z-]+$`)},
+			Value:    "my-app",
+			Expected: "my-app",
+		},
+		{
+			Name:  "not matching regex",
+			Var:   Var{Type: conversion.String, Regex: regexp.MustCompile(`^[a-z-]+$`)},
+			Value: "My App",
+			Error: `value "My App" must match pattern "^[a-z-]+$"`,
+		},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			actual, err := f.Var.Coerce(f.Value)
+
+			if f.Error != "" {
+				assert.EqualError(t, err, f.Error)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, f.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestSchema(t *testing.T) {
+	schema := Schema{
+		"PROJECT":  Var{Name: "PROJECT", Type: conversion.String, Required: true},
+		"REPLICAS": Var{Name: "REPLICAS", Type: conversion.Int, Default: 1},
+		"ENV":      Var{Name: "ENV", Type: conversion.String, Enum: []interface{}{"dev", "prod"}, Required: true},
+	}
+
+	vars := varMap{"ENV": "prod", "OTHER": 123}
+	schema.ApplyDefaults(vars)
+	assert.Equal(t, varMap{"ENV": "prod", "REPLICAS": 1, "OTHER": 123}, vars)
+	assert.Equal(t, []string{"PROJECT"}, schema.GetMissing(vars))
+
+	vars = varMap{"REPLICAS": "3", "ENV": "prod"}
+	assert.NoError(t, schema.Validate(vars))
+	assert.Equal(t, varMap{"REPLICAS": 3, "ENV": "prod"}, vars)
+
+	vars = varMap{"REPLICAS": "abc", "ENV": "staging", "PROJECT": "abc"}
+	assert.EqualError(t, schema.Validate(vars), `found 2 invalid variables:
+invalid variable "ENV": value "staging" must be one of: dev, prod
+invalid variable "REPLICAS": invalid int value "abc"`)
+}
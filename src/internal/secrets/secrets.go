@@ -0,0 +1,97 @@
+package secrets
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/Samasource/jen/src/internal/logging"
+	"gopkg.in/yaml.v2"
+)
+
+// Redacted is displayed instead of the values of secret variables
+const Redacted = "******"
+
+// fileMode restricts access to secrets file to its owner
+const fileMode os.FileMode = 0600
+
+// Store represents the per-user file where values of secret variables are saved, keyed by project dir, so
+// that they never end up in project file
+type Store struct {
+	Path     string                       `yaml:"-"`
+	DryRun   bool                         `yaml:"-"`
+	Projects map[string]map[string]string `yaml:"projects"`
+}
+
+// Load loads the secrets file at given path, returning an empty store if it does not exist yet
+func Load(path string) (*Store, error) {
+	store := &Store{
+		Path:     path,
+		Projects: make(map[string]map[string]string),
+	}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading secrets file: %w", err)
+	}
+	if info.Mode().Perm()&^fileMode != 0 {
+		return nil, fmt.Errorf("secrets file %q must only be accessible by its owner (expected mode %04o, not %04o)", path, fileMode, info.Mode().Perm())
+	}
+
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("loading secrets file: %w", err)
+	}
+	if err := yaml.Unmarshal(buf, store); err != nil {
+		return nil, fmt.Errorf("unmarshalling secrets file yaml: %w", err)
+	}
+	if store.Projects == nil {
+		store.Projects = make(map[string]map[string]string)
+	}
+	return store, nil
+}
+
+// Get returns a copy of the secret variables of given project dir
+func (s *Store) Get(projectDir string) map[string]string {
+	clone := make(map[string]string)
+	for name, value := range s.Projects[projectDir] {
+		clone[name] = value
+	}
+	return clone
+}
+
+// Set sets the value of given secret variable of given project dir and saves the secrets file
+func (s *Store) Set(projectDir, name, value string) error {
+	secrets, ok := s.Projects[projectDir]
+	if !ok {
+		secrets = make(map[string]string)
+		s.Projects[projectDir] = secrets
+	}
+	secrets[name] = value
+	return s.Save()
+}
+
+// Save saves the secrets file, only accessible by its owner, unless in dry-run mode, in which case changes
+// are only kept in memory
+func (s *Store) Save() error {
+	if s.DryRun {
+		logging.Log("Skipping save of secrets file in dry-run mode")
+		return nil
+	}
+	doc, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0700); err != nil {
+		return fmt.Errorf("creating secrets file dir: %w", err)
+	}
+	if err := ioutil.WriteFile(s.Path, doc, fileMode); err != nil {
+		return fmt.Errorf("saving secrets file: %w", err)
+	}
+
+	// Existing file keeps its original mode when written to
+	return os.Chmod(s.Path, fileMode)
+}
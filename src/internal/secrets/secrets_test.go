@@ -0,0 +1,47 @@
+package secrets
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp", "jen_test_")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, ".jen", "secrets.yaml")
+
+	// Missing file
+	store, err := Load(path)
+	assert.NoError(t, err)
+	assert.Empty(t, store.Get("/project1"))
+
+	// Set and reload
+	assert.NoError(t, store.Set("/project1", "TOKEN", "abc"))
+	assert.NoError(t, store.Set("/project2", "TOKEN", "def"))
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	store, err = Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"TOKEN": "abc"}, store.Get("/project1"))
+	assert.Equal(t, map[string]string{"TOKEN": "def"}, store.Get("/project2"))
+
+	// Dry-run
+	store.DryRun = true
+	assert.NoError(t, store.Set("/project1", "PASSWORD", "xyz"))
+	assert.Equal(t, map[string]string{"TOKEN": "abc", "PASSWORD": "xyz"}, store.Get("/project1"))
+	store, err = Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"TOKEN": "abc"}, store.Get("/project1"))
+
+	// Accessible by others
+	assert.NoError(t, os.Chmod(path, 0644))
+	_, err = Load(path)
+	assert.EqualError(t, err, `secrets file "`+path+`" must only be accessible by its owner (expected mode 0600, not 0644)`)
+}
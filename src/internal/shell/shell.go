@@ -1,6 +1,7 @@
 package shell
 
 import (
+	"bytes"
 	"os"
 	"os/exec"
 	"strings"
@@ -33,3 +34,29 @@ func Execute(vars []string, dir string, commands ...string) error {
 	defer logging.Log("--")
 	return cmd.Run()
 }
+
+// ExecuteOutput executes one or multiple shell commands, just like Execute, but captures and returns their standard
+// output instead of displaying it
+func ExecuteOutput(vars []string, dir string, commands ...string) (string, error) {
+	// Env vars default to current process' env vars
+	if vars == nil {
+		vars = os.Environ()
+	}
+
+	// Configure command struct
+	var stdout bytes.Buffer
+	cmd := &exec.Cmd{
+		Path:   "/bin/bash",
+		Args:   []string{"/bin/bash", "-c", "set -e; " + strings.Join(commands, "; ")},
+		Dir:    dir,
+		Env:    vars,
+		Stdin:  os.Stdin,
+		Stdout: &stdout,
+		Stderr: os.Stderr,
+	}
+
+	// Execute
+	logging.Log("Executing command(s) %q in directory %q", commands, dir)
+	err := cmd.Run()
+	return stdout.String(), err
+}
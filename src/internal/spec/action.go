@@ -1,6 +1,9 @@
 package spec
 
 import (
+	"fmt"
+
+	"github.com/Samasource/jen/src/internal/evaluation"
 	"github.com/Samasource/jen/src/internal/exec"
 	logging "github.com/Samasource/jen/src/internal/logging"
 )
@@ -23,5 +26,6 @@ func (a Action) String() string {
 // Execute executes many steps in sequence
 func (a Action) Execute(context exec.Context) error {
 	logging.Log("Executing action %q", a.Name)
-	return a.Steps.Execute(context)
+	err := a.Steps.Execute(context)
+	return evaluation.AddToChain(err, fmt.Sprintf("action %q", a.Name))
 }
@@ -2,6 +2,8 @@ package spec
 
 import (
 	"fmt"
+	"os"
+	"strconv"
 	"strings"
 
 	"github.com/kylelemons/go-gypsy/yaml"
@@ -141,6 +143,14 @@ func getRequiredStringsOrStringFromMap(_map yaml.Map, key string) ([]string, err
 	return values, nil
 }
 
+// getOptionalStrings retrieves a child list of raw strings, also accepting a single raw string
+func getOptionalStrings(_map yaml.Map, key string) ([]string, error) {
+	if _, ok := _map[key]; !ok {
+		return nil, nil
+	}
+	return getRequiredStringsOrStringFromMap(_map, key)
+}
+
 func getOptionalStringFromMap(node yaml.Node, key string, defaultValue string) (string, error) {
 	_map, ok := node.(yaml.Map)
 	if !ok {
@@ -214,3 +224,39 @@ func getOptionalBool(_map yaml.Map, key string, defaultValue bool) (bool, error)
 		return false, fmt.Errorf("invalid bool value: %q", value)
 	}
 }
+
+func getOptionalInt(_map yaml.Map, key string, defaultValue int) (int, error) {
+	value, ok, err := getStringInternal(_map, key)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return defaultValue, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("invalid non-negative int value: %q", value)
+	}
+	return i, nil
+}
+
+// getOptionalModes retrieves a child map of glob patterns mapped to octal file modes (ie: "0755")
+func getOptionalModes(_map yaml.Map, key string) (map[string]os.FileMode, error) {
+	child, ok, err := getOptionalMap(_map, key)
+	if err != nil || !ok {
+		return nil, err
+	}
+	modes := make(map[string]os.FileMode, len(child))
+	for pattern, node := range child {
+		str, ok := getString(node)
+		if !ok {
+			return nil, fmt.Errorf("mode for pattern %q must be a string", pattern)
+		}
+		mode, err := strconv.ParseUint(str, 8, 32)
+		if err != nil || mode > 0777 {
+			return nil, fmt.Errorf("invalid octal mode %q for pattern %q", str, pattern)
+		}
+		modes[pattern] = os.FileMode(mode)
+	}
+	return modes, nil
+}
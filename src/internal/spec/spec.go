@@ -3,9 +3,14 @@ package spec
 import (
 	"fmt"
 	"path/filepath"
+	"regexp"
 
+	"github.com/Samasource/jen/src/internal/casing"
 	"github.com/Samasource/jen/src/internal/constant"
+	"github.com/Samasource/jen/src/internal/evaluation"
 	"github.com/Samasource/jen/src/internal/exec"
+	"github.com/Samasource/jen/src/internal/helpers/conversion"
+	"github.com/Samasource/jen/src/internal/schema"
 	"github.com/Samasource/jen/src/internal/steps"
 	"github.com/Samasource/jen/src/internal/steps/choice"
 	"github.com/Samasource/jen/src/internal/steps/do"
@@ -22,8 +27,20 @@ type Spec struct {
 	Name         string
 	Version      string
 	Description  string
-	Placeholders map[string]string
+	Placeholders evaluation.Placeholders
 	Actions      map[string]Action
+
+	// Strict makes references to undefined variables fail evaluation
+	Strict bool
+
+	// Delimiters are the delimiters of template actions in rendered files and their names
+	Delimiters evaluation.Delimiters
+
+	// Computed maps names of variables derived from other variables to their expressions
+	Computed evaluation.ComputedVars
+
+	// Vars declares the types and constraints of project variables
+	Vars schema.Schema
 }
 
 // Load loads spec object from a template directory
@@ -59,6 +76,22 @@ func loadFromMap(_map yaml.Map, templateDir string) (*Spec, error) {
 		return nil, err
 	}
 
+	spec.Strict, err = getOptionalBool(_map, "strict", false)
+	if err != nil {
+		return nil, err
+	}
+
+	delims, err := getOptionalStringFromMap(_map, "delimiters", "")
+	if err != nil {
+		return nil, err
+	}
+	if delims != "" {
+		spec.Delimiters, err = evaluation.ParseDelimiters(delims)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Load placeholders
 	placeholders, ok, err := getOptionalMap(_map, "placeholders")
 	if err != nil {
@@ -66,6 +99,33 @@ func loadFromMap(_map yaml.Map, templateDir string) (*Spec, error) {
 	}
 	if ok {
 		spec.Placeholders, err = loadPlaceholders(placeholders)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Load variables schema
+	vars, ok, err := getOptionalMap(_map, "vars")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		spec.Vars, err = loadVarSchema(vars)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Load computed variables
+	computed, ok, err := getOptionalMap(_map, "computed")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		spec.Computed, err = loadComputedVars(computed)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Load actions
@@ -78,21 +138,255 @@ func loadFromMap(_map yaml.Map, templateDir string) (*Spec, error) {
 		return nil, err
 	}
 
+	// Values of variables prompted by secret input steps must never be saved to project file
+	for _, action := range spec.Actions {
+		for _, name := range getSecretVars(action.Steps) {
+			if spec.Vars == nil {
+				spec.Vars = make(schema.Schema)
+			}
+			v, ok := spec.Vars[name]
+			if !ok {
+				v = schema.Var{Name: name, Type: conversion.String}
+			}
+			v.Secret = true
+			spec.Vars[name] = v
+		}
+	}
+
 	return spec, nil
 }
 
-func loadPlaceholders(_map yaml.Map) (map[string]string, error) {
-	placeholders := make(map[string]string, len(_map))
-	for key, node := range _map {
+// getSecretVars returns the names of variables prompted by secret input steps among given steps, recursively
+func getSecretVars(executables exec.Executables) []string {
+	var names []string
+	for _, executable := range executables {
+		switch step := executable.(type) {
+		case input.Prompt:
+			if step.Secret {
+				names = append(names, step.Var)
+			}
+		case steps.If:
+			names = append(names, getSecretVars(step.Then)...)
+		case steps.Confirm:
+			names = append(names, getSecretVars(step.Then)...)
+		}
+	}
+	return names
+}
+
+func loadComputedVars(_map yaml.Map) (evaluation.ComputedVars, error) {
+	computed := make(evaluation.ComputedVars, len(_map))
+	for name, node := range _map {
 		value, ok := getString(node)
 		if !ok {
-			return nil, fmt.Errorf("value for placeholder %q must be a string", key)
+			return nil, fmt.Errorf("value of computed variable %q must be a string", name)
+		}
+		computed[name] = value
+	}
+
+	// Detect invalid expressions and circular dependencies early
+	if _, err := computed.Order(); err != nil {
+		return nil, err
+	}
+	return computed, nil
+}
+
+var varTypes = []conversion.Type{conversion.String, conversion.Bool, conversion.Int, conversion.Float, conversion.List, conversion.Map}
+
+func loadVarSchema(_map yaml.Map) (schema.Schema, error) {
+	vars := make(schema.Schema, len(_map))
+	for name, node := range _map {
+		v, err := loadVar(name, node)
+		if err != nil {
+			return nil, fmt.Errorf("loading variable %q: %w", name, err)
+		}
+		vars[name] = *v
+	}
+	return vars, nil
+}
+
+func loadVar(name string, node yaml.Node) (*schema.Var, error) {
+	_map, ok := node.(yaml.Map)
+	if !ok {
+		return nil, fmt.Errorf("variable definition must be an object")
+	}
+	v := &schema.Var{Name: name}
+
+	typ, err := getOptionalStringFromMap(_map, "type", string(conversion.String))
+	if err != nil {
+		return nil, err
+	}
+	v.Type = conversion.Type(typ)
+	if !isValidVarType(v.Type) {
+		return nil, fmt.Errorf("unsupported type %q", typ)
+	}
+
+	v.Description, err = getOptionalStringFromMap(_map, "description", "")
+	if err != nil {
+		return nil, err
+	}
+	v.Required, err = getOptionalBool(_map, "required", false)
+	if err != nil {
+		return nil, err
+	}
+
+	regex, err := getOptionalStringFromMap(_map, "regex", "")
+	if err != nil {
+		return nil, err
+	}
+	if regex != "" {
+		v.Regex, err = regexp.Compile(regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+	}
+
+	enum, err := getOptionalEnum(_map, "enum")
+	if err != nil {
+		return nil, err
+	}
+	for _, text := range enum {
+		value, err := conversion.Parse(text, v.Type)
+		if err != nil {
+			return nil, fmt.Errorf("invalid enum value: %w", err)
+		}
+		v.Enum = append(v.Enum, value)
+	}
+
+	// Default value must itself conform to definition
+	text, ok, err := getStringInternal(_map, "default")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		v.Default, err = v.Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default value: %w", err)
+		}
+	}
+	return v, nil
+}
+
+// getOptionalEnum retrieves a child list of raw strings, also accepting a list in flow format (ie: "[a, b]"),
+// which go-gypsy lib does not support
+func getOptionalEnum(_map yaml.Map, key string) ([]string, error) {
+	if _, ok := _map[key].(yaml.List); ok {
+		return getOptionalStrings(_map, key)
+	}
+	text, ok, err := getStringInternal(_map, key)
+	if err != nil || !ok {
+		return nil, err
+	}
+	list, err := conversion.Parse(text, conversion.List)
+	if err != nil {
+		return nil, fmt.Errorf("property %q must be a list", key)
+	}
+	var items []string
+	for _, item := range list.([]interface{}) {
+		str, err := conversion.ToString(item)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, str)
+	}
+	return items, nil
+}
+
+func isValidVarType(typ conversion.Type) bool {
+	for _, t := range varTypes {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func loadPlaceholders(_map yaml.Map) (evaluation.Placeholders, error) {
+	placeholders := make(evaluation.Placeholders, len(_map))
+	variants := make(evaluation.Placeholders)
+	for key, node := range _map {
+		childMap, ok := node.(yaml.Map)
+		if !ok {
+			value, ok := getString(node)
+			if !ok {
+				return nil, fmt.Errorf("value for placeholder %q must be a string or an object", key)
+			}
+			placeholders[key] = evaluation.Placeholder{Value: value}
+			continue
+		}
+
+		wholeWord, err := getOptionalBool(childMap, "wholeWord", false)
+		if err != nil {
+			return nil, fmt.Errorf("placeholder %q: %w", key, err)
+		}
+		varName, err := getOptionalStringFromMap(childMap, "var", "")
+		if err != nil {
+			return nil, fmt.Errorf("placeholder %q: %w", key, err)
+		}
+		if varName != "" {
+			if _, ok := childMap["value"]; ok {
+				return nil, fmt.Errorf("placeholder %q cannot have both %q and %q properties", key, "var", "value")
+			}
+			if err := addCaseVariants(variants, key, varName, wholeWord); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if _, ok := childMap["value"]; !ok {
+			return nil, fmt.Errorf("placeholder %q must have either a %q or a %q property", key, "var", "value")
+		}
+		value, err := getRequiredStringFromMap(childMap, "value")
+		if err != nil {
+			return nil, fmt.Errorf("placeholder %q: %w", key, err)
+		}
+		placeholders[key] = evaluation.Placeholder{
+			Value:     value,
+			WholeWord: wholeWord,
+		}
+	}
+
+	// Explicit placeholders take precedence over generated variants
+	for key, placeholder := range variants {
+		if _, ok := placeholders[key]; !ok {
+			placeholders[key] = placeholder
 		}
-		placeholders[key] = value
 	}
 	return placeholders, nil
 }
 
+// addCaseVariants adds to given placeholders all case variants of given token, each mapped to an expression
+// formatting given variable in the corresponding case style. The style of the token itself is detected and used as
+// reference for the lower, upper and title variants, so that "my-projekt" yields "MY-PROJEKT" and not "MY PROJEKT".
+func addCaseVariants(placeholders evaluation.Placeholders, token, varName string, wholeWord bool) error {
+	detected, err := casing.Detect(token)
+	if err != nil {
+		return fmt.Errorf("placeholder %q: %w", token, err)
+	}
+	for _, style := range casing.Styles {
+		variant, err := casing.Format(token, style)
+		if err != nil {
+			return err
+		}
+		if _, ok := placeholders[variant]; ok {
+			continue
+		}
+		value := fmt.Sprintf("{{ .%s | toCase %q }}", varName, style)
+		if isSeparatorPreserving(style) && !isSeparatorPreserving(detected) {
+			value = fmt.Sprintf("{{ .%s | toCase %q | toCase %q }}", varName, detected, style)
+		}
+		placeholders[variant] = evaluation.Placeholder{
+			Value:     value,
+			WholeWord: wholeWord,
+		}
+	}
+	return nil
+}
+
+// isSeparatorPreserving determines whether given style only changes letter case, leaving word separators untouched
+func isSeparatorPreserving(style casing.Style) bool {
+	return style == casing.Lower || style == casing.Upper || style == casing.Title
+}
+
 func loadActions(node yaml.Map) (ActionMap, error) {
 	var actions []Action
 	for name, value := range node {
@@ -244,13 +538,79 @@ func loadInputStep(_map yaml.Map) (exec.Executable, error) {
 	if err != nil {
 		return nil, err
 	}
+	var validation input.Validation
+	validate, ok, err := getOptionalMap(_map, "validate")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		validation, err = loadInputValidation(validate)
+		if err != nil {
+			return nil, err
+		}
+	}
+	secret, err := getOptionalBool(_map, "secret", false)
+	if err != nil {
+		return nil, err
+	}
+	if secret && defaultValue != "" {
+		return nil, fmt.Errorf("%q property is not supported for secret input", "default")
+	}
 	return input.Prompt{
-		Message: question,
-		Var:     variable,
-		Default: defaultValue,
+		Message:    question,
+		Var:        variable,
+		Default:    defaultValue,
+		Validation: validation,
+		Secret:     secret,
 	}, nil
 }
 
+func loadInputValidation(_map yaml.Map) (input.Validation, error) {
+	var validation input.Validation
+	var err error
+	validation.Required, err = getOptionalBool(_map, "required", false)
+	if err != nil {
+		return validation, err
+	}
+	validation.MinLength, err = getOptionalInt(_map, "minLength", 0)
+	if err != nil {
+		return validation, err
+	}
+	validation.MaxLength, err = getOptionalInt(_map, "maxLength", 0)
+	if err != nil {
+		return validation, err
+	}
+	if validation.MaxLength > 0 && validation.MinLength > validation.MaxLength {
+		return validation, fmt.Errorf("%q property must not be greater than %q property", "minLength", "maxLength")
+	}
+
+	// Regex can be either a raw pattern or an object with a custom message
+	regex, ok, err := getOptionalMapOrRawStringOrRawStrings(_map, "regex", "pattern")
+	if err != nil {
+		return validation, err
+	}
+	if ok {
+		pattern, err := getRequiredStringFromMap(regex, "pattern")
+		if err != nil {
+			return validation, err
+		}
+		validation.Regex, err = regexp.Compile(pattern)
+		if err != nil {
+			return validation, fmt.Errorf("invalid regex: %w", err)
+		}
+		validation.RegexMessage, err = getOptionalStringFromMap(regex, "message", "")
+		if err != nil {
+			return validation, err
+		}
+	}
+
+	validation.Expression, err = getOptionalStringFromMap(_map, "expression", "")
+	if err != nil {
+		return validation, err
+	}
+	return validation, nil
+}
+
 func loadOptionStep(_map yaml.Map) (exec.Executable, error) {
 	question, err := getRequiredStringFromMap(_map, "question")
 	if err != nil {
@@ -360,6 +720,8 @@ func loadChoiceStep(_map yaml.Map) (exec.Executable, error) {
 	}, nil
 }
 
+var loopVarRegexp = regexp.MustCompile(`^\w+$`)
+
 func loadRenderStep(_map yaml.Map) (exec.Executable, error) {
 	source, err := getRequiredStringFromMap(_map, "source")
 	if err != nil {
@@ -371,9 +733,55 @@ func loadRenderStep(_map yaml.Map) (exec.Executable, error) {
 		return nil, err
 	}
 
+	overwrite, err := getOptionalStringFromMap(_map, "overwrite", "")
+	if err != nil {
+		return nil, err
+	}
+	policy, err := evaluation.ParseOverwritePolicy(overwrite)
+	if err != nil {
+		return nil, err
+	}
+
+	modes, err := getOptionalModes(_map, "modes")
+	if err != nil {
+		return nil, err
+	}
+
+	binary, err := getOptionalStrings(_map, "binary")
+	if err != nil {
+		return nil, err
+	}
+
+	ignore, err := getOptionalStrings(_map, "ignore")
+	if err != nil {
+		return nil, err
+	}
+
+	foreach, err := getOptionalStringFromMap(_map, "foreach", "")
+	if err != nil {
+		return nil, err
+	}
+
+	as, err := getOptionalStringFromMap(_map, "as", "")
+	if err != nil {
+		return nil, err
+	}
+	if as != "" && foreach == "" {
+		return nil, fmt.Errorf("%q property requires %q property", "as", "foreach")
+	}
+	if as != "" && !loopVarRegexp.MatchString(as) {
+		return nil, fmt.Errorf("invalid loop variable name %q", as)
+	}
+
 	return render.Render{
 		InputDir:  source,
 		OutputDir: target,
+		Overwrite: policy,
+		Modes:     modes,
+		Binary:    binary,
+		Ignore:    ignore,
+		Foreach:   foreach,
+		As:        as,
 	}, nil
 }
 
@@ -1,10 +1,15 @@
 package spec
 
 import (
+	"os"
+	"regexp"
 	"strings"
 	"testing"
 
+	"github.com/Samasource/jen/src/internal/evaluation"
 	"github.com/Samasource/jen/src/internal/exec"
+	"github.com/Samasource/jen/src/internal/helpers/conversion"
+	"github.com/Samasource/jen/src/internal/schema"
 	"github.com/Samasource/jen/src/internal/steps"
 	"github.com/Samasource/jen/src/internal/steps/choice"
 	"github.com/Samasource/jen/src/internal/steps/do"
@@ -104,6 +109,83 @@ input:
 				Var:     "Variable",
 			},
 		},
+		{
+			Name: "input prompt with validation",
+			Buffer: `
+input:
+  question: Message
+  var: Variable
+  validate:
+    required: true
+    minLength: 2
+    maxLength: 30
+    regex: ^[a-z-]+$
+    expression: "{{ if eq .Variable .PROJECT }}Must differ from project name{{ end }}"`,
+			Expected: input.Prompt{
+				Message: "Message",
+				Var:     "Variable",
+				Validation: input.Validation{
+					Required:   true,
+					MinLength:  2,
+					MaxLength:  30,
+					Regex:      regexp.MustCompile(`^[a-z-]+$`),
+					Expression: "{{ if eq .Variable .PROJECT }}Must differ from project name{{ end }}",
+				},
+			},
+		},
+		{
+			Name: "input prompt with regex message",
+			Buffer: `
+input:
+  question: Message
+  var: Variable
+  validate:
+    regex:
+      pattern: ^[a-z]+$
+      message: Only lowercase letters are allowed`,
+			Expected: input.Prompt{
+				Message: "Message",
+				Var:     "Variable",
+				Validation: input.Validation{
+					Regex:        regexp.MustCompile(`^[a-z]+$`),
+					RegexMessage: "Only lowercase letters are allowed",
+				},
+			},
+		},
+		{
+			Name: "input prompt with invalid length bounds",
+			Buffer: `
+input:
+  question: Message
+  var: Variable
+  validate:
+    minLength: 10
+    maxLength: 5`,
+			Error: `"minLength" property must not be greater than "maxLength" property`,
+		},
+		{
+			Name: "secret input prompt",
+			Buffer: `
+input:
+  question: Message
+  var: Variable
+  secret: true`,
+			Expected: input.Prompt{
+				Message: "Message",
+				Var:     "Variable",
+				Secret:  true,
+			},
+		},
+		{
+			Name: "secret input prompt with default",
+			Buffer: `
+input:
+  question: Message
+  var: Variable
+  default: Default
+  secret: true`,
+			Error: `"default" property is not supported for secret input`,
+		},
 		{
 			Name: "missing required question property",
 			Buffer: `
@@ -229,6 +311,106 @@ render:
 				InputDir: "Source",
 			},
 		},
+		{
+			Name: "render step with overwrite policy",
+			Buffer: `
+render:
+  source: Source
+  overwrite: skip-existing`,
+			Expected: render.Render{
+				InputDir:  "Source",
+				Overwrite: evaluation.SkipExisting,
+			},
+		},
+		{
+			Name: "render step with invalid overwrite policy",
+			Buffer: `
+render:
+  source: Source
+  overwrite: never`,
+			Error: `invalid overwrite policy "never" (expected one of "overwrite", "skip-existing", "fail-on-existing" or "prompt")`,
+		},
+		{
+			Name: "render step with foreach",
+			Buffer: `
+render:
+  source: Source
+  foreach: .ENDPOINTS
+  as: EP`,
+			Expected: render.Render{
+				InputDir: "Source",
+				Foreach:  ".ENDPOINTS",
+				As:       "EP",
+			},
+		},
+		{
+			Name: "render step with as but no foreach",
+			Buffer: `
+render:
+  source: Source
+  as: EP`,
+			Error: `"as" property requires "foreach" property`,
+		},
+		{
+			Name: "render step with invalid loop variable",
+			Buffer: `
+render:
+  source: Source
+  foreach: .ENDPOINTS
+  as: my-ep`,
+			Error: `invalid loop variable name "my-ep"`,
+		},
+		{
+			Name: "render step with modes",
+			Buffer: `
+render:
+  source: Source
+  modes:
+    bin/*: "0755"
+    *.key: "600"`,
+			Expected: render.Render{
+				InputDir: "Source",
+				Modes: map[string]os.FileMode{
+					"bin/*": 0755,
+					"*.key": 0600,
+				},
+			},
+		},
+		{
+			Name: "render step with invalid mode",
+			Buffer: `
+render:
+  source: Source
+  modes:
+    bin/*: "0955"`,
+			Error: `invalid octal mode "0955" for pattern "bin/*"`,
+		},
+		{
+			Name: "render step with binary patterns",
+			Buffer: `
+render:
+  source: Source
+  binary:
+    - fonts/*
+    - "*.dat"`,
+			Expected: render.Render{
+				InputDir: "Source",
+				Binary:   []string{"fonts/*", "*.dat"},
+			},
+		},
+		{
+			Name: "render step with ignore patterns",
+			Buffer: `
+render:
+  source: Source
+  ignore:
+    - "*.swp"
+    - docs/`,
+			Expected: render.Render{
+				InputDir: "Source",
+				Ignore:   []string{"*.swp", "docs/"},
+			},
+		},
 		{
 			Name: "render step short-hand",
 			Buffer: `
@@ -382,10 +564,10 @@ actions:
 				Name:        "template_name",
 				Version:     "0.2.0",
 				Description: "Description",
-				Placeholders: map[string]string{
-					"projekt": "{{.PROJECT | lower}}",
-					"Projekt": "{{.PROJECT | title}}",
-					"PROJEKT": "{{.PROJECT | upper}}",
+				Placeholders: evaluation.Placeholders{
+					"projekt": {Value: "{{.PROJECT | lower}}"},
+					"Projekt": {Value: "{{.PROJECT | title}}"},
+					"PROJEKT": {Value: "{{.PROJECT | upper}}"},
 				},
 				Actions: ActionMap{
 					"action1": Action{
@@ -414,6 +596,343 @@ actions:
 				},
 			},
 		},
+		{
+			Name: "case variants",
+			Buffer: `
+version: 0.2.0
+description: Description
+placeholders:
+  my-projekt:
+    var: PROJECT
+  MY_PROJEKT: {{.PROJECT | upper}}
+actions:
+  action1:
+    - exec: echo`,
+			Expected: &Spec{
+				Name:        "template_name",
+				Version:     "0.2.0",
+				Description: "Description",
+				Placeholders: evaluation.Placeholders{
+					"my-projekt": {Value: `{{ .PROJECT | toCase "kebab" | toCase "lower" }}`},
+					"MY-PROJEKT": {Value: `{{ .PROJECT | toCase "kebab" | toCase "upper" }}`},
+					"My-Projekt": {Value: `{{ .PROJECT | toCase "kebab" | toCase "title" }}`},
+					"myProjekt":  {Value: `{{ .PROJECT | toCase "camel" }}`},
+					"MyProjekt":  {Value: `{{ .PROJECT | toCase "pascal" }}`},
+					"my_projekt": {Value: `{{ .PROJECT | toCase "snake" }}`},
+					"MY_PROJEKT": {Value: "{{.PROJECT | upper}}"},
+					"my.projekt": {Value: `{{ .PROJECT | toCase "dotted" }}`},
+				},
+				Actions: ActionMap{
+					"action1": Action{
+						Name: "action1",
+						Steps: exec.Executables{
+							execstep.Exec{
+								Commands: []string{"echo"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name: "whole word placeholders",
+			Buffer: `
+version: 0.2.0
+description: Description
+placeholders:
+  projekt:
+    var: PROJECT
+    wholeWord: true
+  app:
+    value: {{.APP}}
+    wholeWord: true
+  svc:
+    value: {{.SERVICE}}
+actions:
+  action1:
+    - exec: echo`,
+			Expected: &Spec{
+				Name:        "template_name",
+				Version:     "0.2.0",
+				Description: "Description",
+				Placeholders: evaluation.Placeholders{
+					"projekt": {Value: `{{ .PROJECT | toCase "lower" }}`, WholeWord: true},
+					"PROJEKT": {Value: `{{ .PROJECT | toCase "upper" }}`, WholeWord: true},
+					"Projekt": {Value: `{{ .PROJECT | toCase "title" }}`, WholeWord: true},
+					"app":     {Value: "{{.APP}}", WholeWord: true},
+					"svc":     {Value: "{{.SERVICE}}"},
+				},
+				Actions: ActionMap{
+					"action1": Action{
+						Name: "action1",
+						Steps: exec.Executables{
+							execstep.Exec{
+								Commands: []string{"echo"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name: "strict",
+			Buffer: `
+version: 0.2.0
+description: Description
+strict: true
+actions:
+  action1:
+    - exec: echo`,
+			Expected: &Spec{
+				Name:        "template_name",
+				Version:     "0.2.0",
+				Description: "Description",
+				Strict:      true,
+				Actions: ActionMap{
+					"action1": Action{
+						Name: "action1",
+						Steps: exec.Executables{
+							execstep.Exec{
+								Commands: []string{"echo"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name: "case variants with undetectable style",
+			Buffer: `
+version: 0.2.0
+description: Description
+placeholders:
+  My projekt:
+    var: PROJECT
+actions:
+  action1:
+    - exec: echo`,
+			Error: `placeholder "My projekt": cannot detect case style of "My projekt"`,
+		},
+		{
+			Name: "placeholder object without var or value",
+			Buffer: `
+version: 0.2.0
+description: Description
+placeholders:
+  projekt:
+    name: PROJECT
+actions:
+  action1:
+    - exec: echo`,
+			Error: `placeholder "projekt" must have either a "var" or a "value" property`,
+		},
+		{
+			Name: "delimiters",
+			Buffer: `
+version: 0.2.0
+description: Description
+delimiters: "[% %]"
+actions:
+  action1:
+    - exec: echo`,
+			Expected: &Spec{
+				Name:        "template_name",
+				Version:     "0.2.0",
+				Description: "Description",
+				Delimiters:  evaluation.Delimiters{Left: "[%", Right: "%]"},
+				Actions: ActionMap{
+					"action1": Action{
+						Name: "action1",
+						Steps: exec.Executables{
+							execstep.Exec{
+								Commands: []string{"echo"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name: "computed",
+			Buffer: `
+version: 0.2.0
+description: Description
+computed:
+  SLUG: "{{ .PROJECT | lower }}"
+  IMAGE: "registry/{{ .SLUG }}"
+actions:
+  action1:
+    - exec: echo`,
+			Expected: &Spec{
+				Name:        "template_name",
+				Version:     "0.2.0",
+				Description: "Description",
+				Computed: evaluation.ComputedVars{
+					"SLUG":  "{{ .PROJECT | lower }}",
+					"IMAGE": "registry/{{ .SLUG }}",
+				},
+				Actions: ActionMap{
+					"action1": Action{
+						Name: "action1",
+						Steps: exec.Executables{
+							execstep.Exec{
+								Commands: []string{"echo"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name: "computed with circular dependency",
+			Buffer: `
+version: 0.2.0
+description: Description
+computed:
+  A: "{{ .B }}"
+  B: "{{ .A }}"
+actions:
+  action1:
+    - exec: echo`,
+			Error: "computed variables have a circular dependency: A -> B -> A",
+		},
+		{
+			Name: "vars",
+			Buffer: `
+version: 0.2.0
+description: Description
+vars:
+  PROJECT:
+    description: Name of project
+    required: true
+    regex: ^[a-z][a-z0-9-]*$
+  REPLICAS:
+    type: int
+    default: 2
+    enum: [1, 2, 3]
+  HOSTS:
+    type: list
+    default: "[a.com, b.com]"
+actions:
+  action1:
+    - exec: echo`,
+			Expected: &Spec{
+				Name:        "template_name",
+				Version:     "0.2.0",
+				Description: "Description",
+				Vars: schema.Schema{
+					"PROJECT": schema.Var{
+						Name:        "PROJECT",
+						Type:        conversion.String,
+						Description: "Name of project",
+						Required:    true,
+						Regex:       regexp.MustCompile(`^[a-z][a-z0-9-]*$`),
+					},
+					"REPLICAS": schema.Var{
+						Name:    "REPLICAS",
+						Type:    conversion.Int,
+						Default: 2,
+						Enum:    []interface{}{1, 2, 3},
+					},
+					"HOSTS": schema.Var{
+						Name:    "HOSTS",
+						Type:    conversion.List,
+						Default: []interface{}{"a.com", "b.com"},
+					},
+				},
+				Actions: ActionMap{
+					"action1": Action{
+						Name: "action1",
+						Steps: exec.Executables{
+							execstep.Exec{
+								Commands: []string{"echo"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name: "secret input var",
+			Buffer: `
+version: 0.2.0
+description: Description
+actions:
+  action1:
+    - if: true
+      then:
+        - input:
+            question: Token
+            var: TOKEN
+            secret: true`,
+			Expected: &Spec{
+				Name:        "template_name",
+				Version:     "0.2.0",
+				Description: "Description",
+				Vars: schema.Schema{
+					"TOKEN": schema.Var{
+						Name:   "TOKEN",
+						Type:   conversion.String,
+						Secret: true,
+					},
+				},
+				Actions: ActionMap{
+					"action1": Action{
+						Name: "action1",
+						Steps: exec.Executables{
+							steps.If{
+								Condition: "true",
+								Then: exec.Executables{
+									input.Prompt{
+										Message: "Token",
+										Var:     "TOKEN",
+										Secret:  true,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name: "vars with unsupported type",
+			Buffer: `
+version: 0.2.0
+description: Description
+vars:
+  PORT:
+    type: integer
+actions:
+  action1:
+    - exec: echo`,
+			Error: `loading variable "PORT": unsupported type "integer"`,
+		},
+		{
+			Name: "vars with invalid default",
+			Buffer: `
+version: 0.2.0
+description: Description
+vars:
+  ENV:
+    enum: [dev, prod]
+    default: staging
+actions:
+  action1:
+    - exec: echo`,
+			Error: `loading variable "ENV": invalid default value: value "staging" must be one of: dev, prod`,
+		},
+		{
+			Name: "invalid delimiters",
+			Buffer: `
+version: 0.2.0
+description: Description
+delimiters: "[%"
+actions:
+  action1:
+    - exec: echo`,
+			Error: `invalid delimiters "[%" (expected left and right delimiters separated by a space, ie: "[% %]")`,
+		},
 	}
 
 	run(t, fixtures, func(m yaml.Map) (interface{}, error) {
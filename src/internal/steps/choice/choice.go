@@ -4,6 +4,7 @@ import (
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/Samasource/jen/src/internal/evaluation"
 	"github.com/Samasource/jen/src/internal/exec"
+	"github.com/Samasource/jen/src/internal/helpers/variables"
 )
 
 // Item represent one of the multiple choices prompted to user
@@ -33,7 +34,7 @@ func (p Prompt) Execute(context exec.Context) error {
 
 	// Collect option texts and find default index
 	defaultIndex := 0
-	currentValue, _ := context.GetVars()[p.Var]
+	currentValue, _ := variables.TryGetString(context.GetVars(), p.Var)
 	var options []string
 	for i, item := range p.Items {
 		text, err := evaluation.EvalTemplate(context, item.Text)
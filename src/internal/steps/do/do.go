@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/Samasource/jen/src/internal/exec"
+	"github.com/Samasource/jen/src/internal/logging"
 )
 
 // Do represents a reference to another action within same spec file to which
@@ -18,10 +19,13 @@ func (d Do) String() string {
 
 // Execute executes another action with given name within same spec file
 func (d Do) Execute(context exec.Context) error {
-	for _, action := range d.Actions {
-		action := context.GetAction(action)
+	for _, name := range d.Actions {
+		action := context.GetAction(name)
 		if action == nil {
-			return fmt.Errorf("action %q not found for do step", action)
+			return fmt.Errorf("action %q not found for do step", name)
+		}
+		if context.IsDryRun() {
+			logging.Plan("Would enter action %q", name)
 		}
 		err := action.Execute(context)
 		if err != nil {
@@ -1,7 +1,11 @@
 package exec
 
 import (
+	"strings"
+
 	"github.com/Samasource/jen/src/internal/exec"
+	"github.com/Samasource/jen/src/internal/logging"
+	"github.com/Samasource/jen/src/internal/secrets"
 	"github.com/Samasource/jen/src/internal/shell"
 )
 
@@ -16,5 +20,26 @@ func (e Exec) String() string {
 
 // Execute runs one or multiple shell commands with project's variables and bin dirs
 func (e Exec) Execute(context exec.Context) error {
-	return shell.Execute(context.GetShellVars(true), context.GetProjectDir(), e.Commands...)
+	if context.IsDryRun() {
+		logging.Plan("Would execute command(s) %q in directory %q with environment:", e.Commands, context.GetProjectDir())
+		env, err := context.GetShellVars(false)
+		if err != nil {
+			return err
+		}
+		secretVars := context.GetSecrets()
+		for _, entry := range env {
+			// Never reveal values of secret variables
+			name := strings.SplitN(entry, "=", 2)[0]
+			if _, ok := secretVars[name]; ok {
+				entry = name + "=" + secrets.Redacted
+			}
+			logging.Plan("  %s", entry)
+		}
+		return nil
+	}
+	env, err := context.GetShellVars(true)
+	if err != nil {
+		return err
+	}
+	return shell.Execute(env, context.GetProjectDir(), e.Commands...)
 }
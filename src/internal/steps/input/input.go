@@ -1,17 +1,26 @@
 package input
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/Samasource/jen/src/internal/evaluation"
 	"github.com/Samasource/jen/src/internal/exec"
+	"github.com/Samasource/jen/src/internal/helpers/conversion"
 	"github.com/Samasource/jen/src/internal/helpers/variables"
 )
 
 // Prompt represents a single text input user prompt
 type Prompt struct {
-	Message string
-	Var     string
-	Default string
+	Message    string
+	Var        string
+	Default    string
+	Validation Validation
+
+	// Secret masks input and saves value in per-user secrets file, rather
+	// than in project file
+	Secret bool
 }
 
 func (p Prompt) String() string {
@@ -20,7 +29,16 @@ func (p Prompt) String() string {
 
 // Execute prompts user for input value
 func (p Prompt) Execute(context exec.Context) error {
+	if p.Secret {
+		return p.executeSecret(context)
+	}
+
+	// Values overridden via command line must also conform to validation rules
 	if context.IsVarOverriden(p.Var) {
+		value, _ := variables.TryGetString(context.GetVars(), p.Var)
+		if err := p.Validation.Validate(context, p.Var, value); err != nil {
+			return fmt.Errorf("invalid value for variable %q: %w", p.Var, err)
+		}
 		return nil
 	}
 
@@ -32,6 +50,19 @@ func (p Prompt) Execute(context exec.Context) error {
 
 	vars := context.GetVars()
 
+	// Answer is converted to type declared in schema, or otherwise to type of
+	// existing value (ie: lists are edited in json format)
+	typ := conversion.String
+	if value, ok := vars[p.Var]; ok {
+		typ = conversion.TypeOf(value)
+	}
+	parse := func(text string) (interface{}, error) {
+		return conversion.Parse(text, typ)
+	}
+	if v, ok := context.GetVarSchema()[p.Var]; ok {
+		parse = v.Parse
+	}
+
 	// Compute default value
 	defaultValue, ok := variables.TryGetString(vars, p.Var)
 	if !ok {
@@ -46,11 +77,66 @@ func (p Prompt) Execute(context exec.Context) error {
 		Message: message,
 		Default: defaultValue,
 	}
-	value := ""
-	if err := survey.AskOne(prompt, &value); err != nil {
+	text := ""
+	validator := func(answer interface{}) error {
+		if err := p.Validation.Validate(context, p.Var, answer.(string)); err != nil {
+			return err
+		}
+		_, err := parse(answer.(string))
+		return err
+	}
+	if err := survey.AskOne(prompt, &text, survey.WithValidator(validator)); err != nil {
+		return err
+	}
+	value, err := parse(text)
+	if err != nil {
 		return err
 	}
 
 	vars[p.Var] = value
 	return context.SetVars(vars)
 }
+
+// executeSecret prompts user for secret value, unless already defined as env
+// var, with masked input
+func (p Prompt) executeSecret(context exec.Context) error {
+	// Values overridden via command line were saved straight to secrets file
+	if context.IsVarOverriden(p.Var) {
+		value := context.GetSecrets()[p.Var]
+		if err := p.Validation.Validate(context, p.Var, value); err != nil {
+			return fmt.Errorf("invalid value for variable %q: %w", p.Var, err)
+		}
+		return nil
+	}
+	if _, ok := os.LookupEnv(p.Var); ok {
+		return nil
+	}
+
+	message, err := evaluation.EvalTemplate(context, p.Message)
+	if err != nil {
+		return err
+	}
+	_, exists := context.GetSecrets()[p.Var]
+	if exists {
+		message += " (leave empty to keep current value)"
+	}
+
+	// Show prompt
+	prompt := &survey.Password{
+		Message: message,
+	}
+	value := ""
+	validator := func(answer interface{}) error {
+		if exists && answer.(string) == "" {
+			return nil
+		}
+		return p.Validation.Validate(context, p.Var, answer.(string))
+	}
+	if err := survey.AskOne(prompt, &value, survey.WithValidator(validator)); err != nil {
+		return err
+	}
+	if exists && value == "" {
+		return nil
+	}
+	return context.SetSecret(p.Var, value)
+}
@@ -0,0 +1,64 @@
+package input
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Samasource/jen/src/internal/evaluation"
+)
+
+// Validation represents the rules that values entered in an input prompt (or
+// overridden via command line) must conform to
+type Validation struct {
+	// Required prevents empty (or blank) values
+	Required bool
+
+	// MinLength and MaxLength are the bounds of value's length in characters,
+	// where zero means no bound
+	MinLength int
+	MaxLength int
+
+	// Regex is the pattern that values must match, if any, and RegexMessage the
+	// error message to report when they don't, instead of the default one
+	Regex        *regexp.Regexp
+	RegexMessage string
+
+	// Expression is a template expression returning an error message, or an
+	// empty string for valid values, with value available in the prompt's own
+	// variable
+	Expression string
+}
+
+// Validate returns an error if given value, to be assigned to given variable,
+// does not conform to validation rules
+func (v Validation) Validate(context evaluation.Context, name, value string) error {
+	if v.Required && strings.TrimSpace(value) == "" {
+		return fmt.Errorf("value is required")
+	}
+	length := utf8.RuneCountInString(value)
+	if v.MinLength > 0 && length < v.MinLength {
+		return fmt.Errorf("value must be at least %d characters long", v.MinLength)
+	}
+	if v.MaxLength > 0 && length > v.MaxLength {
+		return fmt.Errorf("value must be at most %d characters long", v.MaxLength)
+	}
+	if v.Regex != nil && !v.Regex.MatchString(value) {
+		if v.RegexMessage != "" {
+			return fmt.Errorf("%s", v.RegexMessage)
+		}
+		return fmt.Errorf("value must match pattern %q", v.Regex)
+	}
+	if v.Expression != "" {
+		vars := map[string]interface{}{name: value}
+		message, err := evaluation.EvalTemplate(evaluation.WithVars(context, vars), v.Expression)
+		if err != nil {
+			return err
+		}
+		if message = strings.TrimSpace(message); message != "" {
+			return fmt.Errorf("%s", message)
+		}
+	}
+	return nil
+}
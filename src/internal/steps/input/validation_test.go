@@ -0,0 +1,133 @@
+package input
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/Samasource/jen/src/internal/evaluation"
+	"github.com/Samasource/jen/src/internal/vfs"
+	"github.com/stretchr/testify/assert"
+)
+
+type context struct {
+	vars map[string]interface{}
+}
+
+func (c context) GetEvalVars() (map[string]interface{}, error) {
+	return c.vars, nil
+}
+
+func (c context) GetPlaceholders() evaluation.Placeholders {
+	return nil
+}
+
+func (c context) GetShellVars(includeProcessVars bool) ([]string, error) {
+	return nil, nil
+}
+
+func (c context) IsDryRun() bool {
+	return false
+}
+
+func (c context) GetPartials() []evaluation.Partial {
+	return nil
+}
+
+func (c context) IsStrict() bool {
+	return false
+}
+
+func (c context) GetDelimiters() evaluation.Delimiters {
+	return evaluation.Delimiters{}
+}
+
+func (c context) GetTemplateDir() string {
+	return ""
+}
+
+func (c context) GetProjectDir() string {
+	return ""
+}
+
+func (c context) GetProjectFileSystem() vfs.Reader {
+	return vfs.OS{}
+}
+
+func TestValidate(t *testing.T) {
+	context := context{
+		vars: map[string]interface{}{
+			"TEAM": "devops",
+		},
+	}
+
+	fixtures := []struct {
+		Name       string
+		Validation Validation
+		Value      string
+		Error      string
+	}{
+		{
+			Name:       "no rules",
+			Validation: Validation{},
+			Value:      "",
+		},
+		{
+			Name:       "required",
+			Validation: Validation{Required: true},
+			Value:      "  ",
+			Error:      "value is required",
+		},
+		{
+			Name:       "min length",
+			Validation: Validation{MinLength: 3},
+			Value:      "ab",
+			Error:      "value must be at least 3 characters long",
+		},
+		{
+			Name:       "max length in characters",
+			Validation: Validation{MaxLength: 3},
+			Value:      "été",
+		},
+		{
+			Name:       "max length exceeded",
+			Validation: Validation{MaxLength: 3},
+			Value:      "abcd",
+			Error:      "value must be at most 3 characters long",
+		},
+		{
+			Name:       "regex",
+			Validation: Validation{Regex: regexp.MustCompile(`^[a-z-]+$`)},
+			Value:      "My App",
+			Error:      `value must match pattern "^[a-z-]+$"`,
+		},
+		{
+			Name:       "regex with custom message",
+			Validation: Validation{Regex: regexp.MustCompile(`^[a-z-]+$`), RegexMessage: "Only lowercase letters and dashes"},
+			Value:      "My App",
+			Error:      "Only lowercase letters and dashes",
+		},
+		{
+			Name:       "expression",
+			Validation: Validation{Expression: `{{ if hasPrefix .TEAM .PROJECT }}Must not start with team name{{ end }}`},
+			Value:      "devops-app",
+			Error:      "Must not start with team name",
+		},
+		{
+			Name:       "valid expression",
+			Validation: Validation{Expression: `{{ if hasPrefix .TEAM .PROJECT }}Must not start with team name{{ end }}`},
+			Value:      "app",
+		},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			err := f.Validation.Validate(context, "PROJECT", f.Value)
+
+			if f.Error != "" {
+				assert.EqualError(t, err, f.Error)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -1,10 +1,15 @@
 package render
 
 import (
+	"fmt"
+	"os"
 	"path/filepath"
 
 	"github.com/Samasource/jen/src/internal/evaluation"
 	"github.com/Samasource/jen/src/internal/exec"
+	"github.com/Samasource/jen/src/internal/home"
+	"github.com/Samasource/jen/src/internal/manifest"
+	"github.com/Samasource/jen/src/internal/vfs"
 )
 
 // Render represents an executable that renders a given source sub-folder
@@ -12,6 +17,16 @@ import (
 type Render struct {
 	InputDir  string
 	OutputDir string
+	Overwrite evaluation.OverwritePolicy
+	Modes     map[string]os.FileMode
+	Binary    []string
+	Ignore    []string
+
+	// Foreach is an expression resulting in a list, for rendering input dir once per item, or empty
+	Foreach string
+
+	// As is the name of the variable holding current item when looping with Foreach
+	As string
 }
 
 func (r Render) String() string {
@@ -23,5 +38,77 @@ func (r Render) String() string {
 func (r Render) Execute(context exec.Context) error {
 	inputDir := filepath.Join(context.GetTemplateDir(), r.InputDir)
 	outputDir := filepath.Join(context.GetProjectDir(), r.OutputDir)
-	return evaluation.Render(context, inputDir, outputDir)
+
+	// Overwrite policy specified on command line takes precedence over the step's own and those of files
+	policy := context.GetOverwritePolicy()
+	forced := policy != evaluation.DefaultOverwrite
+	if !forced {
+		policy = r.Overwrite
+	}
+
+	// Render into an overlay, so that project only gets modified once whole rendering succeeded
+	output := vfs.NewOverlay(vfs.OS{})
+	files, err := evaluation.Render(context, inputDir, outputDir, evaluation.RenderOptions{
+		Overwrite:       policy,
+		OverwriteForced: forced,
+		Modes:           r.Modes,
+		Binary:          r.Binary,
+		Ignore:          r.Ignore,
+		Foreach:         r.Foreach,
+		As:              r.As,
+		Output:          output,
+	})
+	if err != nil {
+		return err
+	}
+	if context.IsDryRun() {
+		return nil
+	}
+	if err := output.Commit(); err != nil {
+		return fmt.Errorf("failed to write rendered files to project: %w", err)
+	}
+	return r.record(context, files)
+}
+
+// record saves rendered files into project's manifest, to allow for later upgrading
+// them to newer versions of template
+func (r Render) record(context exec.Context, files []evaluation.RenderedFile) error {
+	projectDir := context.GetProjectDir()
+	m, err := manifest.Load(projectDir)
+	if err != nil {
+		return err
+	}
+
+	commit, err := home.GetRepoCommit()
+	if err != nil {
+		return err
+	}
+	m.SetRender(manifest.Render{
+		Source:    r.InputDir,
+		Target:    r.OutputDir,
+		Commit:    commit,
+		Ignore:    r.Ignore,
+		Foreach:   r.Foreach,
+		As:        r.As,
+		Overwrite: string(r.Overwrite),
+		Modes:     manifest.FormatModes(r.Modes),
+		Binary:    r.Binary,
+	})
+
+	for _, file := range files {
+		source, err := filepath.Rel(context.GetTemplateDir(), file.InputPath)
+		if err != nil {
+			return fmt.Errorf("failed to determine template-relative path of %q: %w", file.InputPath, err)
+		}
+		path, err := filepath.Rel(projectDir, file.OutputPath)
+		if err != nil {
+			return fmt.Errorf("failed to determine project-relative path of %q: %w", file.OutputPath, err)
+		}
+		m.Files[path] = manifest.File{
+			Source: source,
+			Hash:   file.Hash,
+		}
+	}
+
+	return m.Save(projectDir)
 }
@@ -0,0 +1,353 @@
+package upgrade
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/Samasource/jen/src/internal/constant"
+	"github.com/Samasource/jen/src/internal/diff"
+	"github.com/Samasource/jen/src/internal/evaluation"
+	"github.com/Samasource/jen/src/internal/helpers"
+	"github.com/Samasource/jen/src/internal/home"
+	"github.com/Samasource/jen/src/internal/logging"
+	"github.com/Samasource/jen/src/internal/manifest"
+	"github.com/Samasource/jen/src/internal/vfs"
+)
+
+// ContextFactory creates a context for rendering given template dir with project's variables
+type ContextFactory func(templateDir string) (evaluation.Context, error)
+
+// Upgrade re-renders all render steps recorded in project's manifest, both at the templates repo commit they were
+// originally rendered from and at the current commit, and then performs a three-way merge of template changes into
+// project files, leaving conflict markers wherever those changes overlap with local modifications.
+func Upgrade(projectDir, templateDir string, newContext ContextFactory, dryRun bool) error {
+	m, err := manifest.Load(projectDir)
+	if err != nil {
+		return err
+	}
+	if len(m.Renders) == 0 {
+		return fmt.Errorf("no render recorded in %q, nothing to upgrade", constant.ManifestFileName)
+	}
+
+	commit, err := home.GetRepoCommit()
+	if err != nil {
+		return err
+	}
+
+	scratchDir, err := ioutil.TempDir("", "jen_upgrade_")
+	if err != nil {
+		return fmt.Errorf("failed to create scratch dir: %w", err)
+	}
+	defer os.RemoveAll(scratchDir)
+
+	// Render previous and current revisions of template into scratch dirs
+	oldDir := filepath.Join(scratchDir, "old")
+	newDir := filepath.Join(scratchDir, "new")
+	oldTemplateDirs := make(map[string]string)
+	for _, r := range m.Renders {
+		oldTemplateDir, ok := oldTemplateDirs[r.Commit]
+		if !ok {
+			exportDir := filepath.Join(scratchDir, "commits", r.Commit)
+			if err := os.MkdirAll(exportDir, 0755); err != nil {
+				return fmt.Errorf("failed to create export dir: %w", err)
+			}
+			oldTemplateDir, err = home.ExportTemplateDir(r.Commit, templateDir, exportDir)
+			if err != nil {
+				return err
+			}
+			oldTemplateDirs[r.Commit] = oldTemplateDir
+		}
+		if _, err := renderInto(newContext, oldTemplateDir, r, oldDir); err != nil {
+			return fmt.Errorf("failed to render template at previous commit %q: %w", r.Commit, err)
+		}
+	}
+	newFiles := make(map[string]evaluation.RenderedFile)
+	skipExisting := make(map[string]bool)
+	for _, r := range m.Renders {
+		files, err := renderInto(newContext, templateDir, r, newDir)
+		if err != nil {
+			return fmt.Errorf("failed to render template at current commit %q: %w", commit, err)
+		}
+		for _, file := range files {
+			path, err := filepath.Rel(newDir, file.OutputPath)
+			if err != nil {
+				return err
+			}
+			newFiles[path] = file
+			if evaluation.OverwritePolicy(r.Overwrite) == evaluation.SkipExisting {
+				skipExisting[path] = true
+			}
+		}
+	}
+
+	// Merge changes into project
+	u := upgrader{
+		projectDir: projectDir,
+		oldDir:     oldDir,
+		manifest:   m,
+		dryRun:     dryRun,
+	}
+	for _, path := range getPaths(oldDir, newFiles) {
+		file, ok := newFiles[path]
+		switch {
+		case ok && skipExisting[path] && helpers.PathExists(filepath.Join(projectDir, path)):
+			logging.Log("Keeping %q (rendered with %q policy)", path, evaluation.SkipExisting)
+		case ok:
+			err = u.upgradeFile(path, file.OutputPath)
+		default:
+			err = u.removeFile(path)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if u.conflicts > 0 {
+		fmt.Printf("%d file(s) have conflicts, please resolve conflict markers manually\n", u.conflicts)
+	}
+	if dryRun {
+		return nil
+	}
+
+	// Update manifest to reflect new revision of template
+	for _, r := range m.Renders {
+		r.Commit = commit
+		m.SetRender(r)
+	}
+	for path, file := range newFiles {
+		source, err := filepath.Rel(templateDir, file.InputPath)
+		if err != nil {
+			return fmt.Errorf("failed to determine template-relative path of %q: %w", file.InputPath, err)
+		}
+		m.Files[path] = manifest.File{
+			Source: source,
+			Hash:   file.Hash,
+		}
+	}
+	return m.Save(projectDir)
+}
+
+// renderInto renders given recorded render step of given template dir into a scratch dir mirroring project dir,
+// with same options as originally
+func renderInto(newContext ContextFactory, templateDir string, r manifest.Render, outputDir string) ([]evaluation.RenderedFile, error) {
+	context, err := newContext(templateDir)
+	if err != nil {
+		return nil, err
+	}
+	modes, err := r.GetModes()
+	if err != nil {
+		return nil, err
+	}
+
+	// Within scratch dir, only skipping existing files is relevant, as other policies are about project files,
+	// which are merged afterwards, and must not prompt or fail
+	policy := evaluation.OverwriteExisting
+	if evaluation.OverwritePolicy(r.Overwrite) == evaluation.SkipExisting {
+		policy = evaluation.SkipExisting
+	}
+	return evaluation.Render(context, filepath.Join(templateDir, r.Source), filepath.Join(outputDir, r.Target), evaluation.RenderOptions{
+		Overwrite:       policy,
+		OverwriteForced: true,
+		SkipInserts:     true,
+		Ignore:          r.Ignore,
+		Foreach:         r.Foreach,
+		As:              r.As,
+		Modes:           modes,
+		Binary:          r.Binary,
+	})
+}
+
+// getPaths returns the sorted union of paths rendered in old scratch dir and in new revision of template (symlinks are
+// not tracked, as they are simply recreated on every render)
+func getPaths(oldDir string, newFiles map[string]evaluation.RenderedFile) []string {
+	set := make(map[string]bool)
+	for path := range newFiles {
+		set[path] = true
+	}
+	_ = filepath.Walk(oldDir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			if rel, err := filepath.Rel(oldDir, path); err == nil {
+				set[rel] = true
+			}
+		}
+		return nil
+	})
+
+	paths := make([]string, 0, len(set))
+	for path := range set {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// upgrader applies template changes to individual project files
+type upgrader struct {
+	projectDir string
+	oldDir     string
+	manifest   *manifest.Manifest
+	dryRun     bool
+	conflicts  int
+}
+
+// upgradeFile merges changes between previous and new renders of given project file. Files are compared by hashes
+// of their streamed content, so that only text files needing an actual merge get loaded into memory.
+func (u *upgrader) upgradeFile(path, newPath string) error {
+	info, err := os.Stat(newPath)
+	if err != nil {
+		return err
+	}
+	newHash, err := evaluation.HashFile(vfs.OS{}, newPath)
+	if err != nil {
+		return err
+	}
+	oldPath := filepath.Join(u.oldDir, path)
+	oldHash, oldExists, err := hashOptionalFile(oldPath)
+	if err != nil {
+		return err
+	}
+	currentPath := filepath.Join(u.projectDir, path)
+	currentHash, currentExists, err := hashOptionalFile(currentPath)
+	if err != nil {
+		return err
+	}
+	_, wasRendered := u.manifest.Files[path]
+
+	switch {
+	case !currentExists && (oldExists || wasRendered):
+		u.report("Skipping %q (deleted locally)", "Would skip %q (deleted locally)", path)
+		return nil
+	case !currentExists:
+		u.report("Creating %q", "Would create %q", path)
+		return u.copy(path, newPath, info.Mode())
+	case currentHash == newHash:
+		logging.Log("File %q already up to date", path)
+		return nil
+	case oldExists && oldHash == newHash:
+		logging.Log("File %q unchanged in template, keeping local version", path)
+		return nil
+	case oldExists && currentHash == oldHash:
+		u.report("Updating %q", "Would update %q", path)
+		return u.copy(path, newPath, info.Mode())
+	}
+
+	// Binary files cannot be merged, so local version prevails
+	for _, p := range []string{newPath, currentPath} {
+		binary, err := evaluation.HasBinaryContent(vfs.OS{}, p)
+		if err != nil {
+			return err
+		}
+		if binary {
+			u.conflicts++
+			u.report("Keeping %q (binary file modified both locally and in template)", "Would keep %q (binary file modified both locally and in template)", path)
+			return nil
+		}
+	}
+
+	// Both template and local file changed, so merge them (without a previous render,
+	// an empty base means the whole file conflicts)
+	newText, err := ioutil.ReadFile(newPath)
+	if err != nil {
+		return err
+	}
+	oldText, _, err := readOptionalFile(oldPath)
+	if err != nil {
+		return err
+	}
+	currentText, _, err := readOptionalFile(currentPath)
+	if err != nil {
+		return err
+	}
+	merged, conflicts := diff.Merge3(oldText, currentText, string(newText))
+	if conflicts {
+		u.conflicts++
+		u.report("Merging %q with conflicts", "Would merge %q with conflicts", path)
+	} else {
+		u.report("Merging %q", "Would merge %q", path)
+	}
+	return u.write(path, merged, info.Mode())
+}
+
+// removeFile removes given project file that is no longer part of template, unless it was modified locally
+func (u *upgrader) removeFile(path string) error {
+	oldHash, _, err := hashOptionalFile(filepath.Join(u.oldDir, path))
+	if err != nil {
+		return err
+	}
+	currentPath := filepath.Join(u.projectDir, path)
+	currentHash, currentExists, err := hashOptionalFile(currentPath)
+	if err != nil {
+		return err
+	}
+	delete(u.manifest.Files, path)
+
+	if !currentExists {
+		return nil
+	}
+	if currentHash != oldHash {
+		u.report("Keeping %q (removed from template, but modified locally)", "Would keep %q (removed from template, but modified locally)", path)
+		return nil
+	}
+	u.report("Removing %q (removed from template)", "Would remove %q (removed from template)", path)
+	if u.dryRun {
+		return nil
+	}
+	return os.Remove(currentPath)
+}
+
+func (u *upgrader) write(path, text string, mode os.FileMode) error {
+	if u.dryRun {
+		return nil
+	}
+	outputPath := filepath.Join(u.projectDir, path)
+	if err := os.MkdirAll(filepath.Dir(outputPath), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create output directory for %q: %w", outputPath, err)
+	}
+	return ioutil.WriteFile(outputPath, []byte(text), mode)
+}
+
+// copy streams given newly rendered file to given project file, without loading it into memory
+func (u *upgrader) copy(path, newPath string, mode os.FileMode) error {
+	if u.dryRun {
+		return nil
+	}
+	outputPath := filepath.Join(u.projectDir, path)
+	if err := os.MkdirAll(filepath.Dir(outputPath), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create output directory for %q: %w", outputPath, err)
+	}
+	return vfs.CopyFile(vfs.OS{}, newPath, vfs.OS{}, outputPath, mode)
+}
+
+// report displays given message, or its dry-run counterpart when in dry-run mode
+func (u *upgrader) report(message, dryRunMessage, path string) {
+	if u.dryRun {
+		logging.Plan(dryRunMessage, path)
+	} else {
+		fmt.Printf(message+"\n", path)
+	}
+}
+
+// hashOptionalFile returns the SHA-256 hex digest of given file's content and whether it exists
+func hashOptionalFile(path string) (string, bool, error) {
+	hash, err := evaluation.HashFile(vfs.OS{}, path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return hash, true, nil
+}
+
+func readOptionalFile(path string) (string, bool, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return string(buf), true, nil
+}
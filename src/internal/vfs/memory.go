@@ -0,0 +1,364 @@
+package vfs
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// maxSymlinks is the maximum number of symlinks followed while resolving a path, to detect loops
+const maxSymlinks = 40
+
+// Memory is a file system held entirely in memory, which is useful for previews and tests. Only symlinks appearing as
+// the last component of a path are followed.
+type Memory struct {
+	mutex sync.RWMutex
+	nodes map[string]*node
+}
+
+type node struct {
+	mode    os.FileMode
+	data    []byte
+	link    string
+	modTime time.Time
+
+	// source is the file that node's content gets read from lazily, instead of data, for files copied from
+	// another file system
+	source *source
+
+	// implicit indicates a dir that was only created to hold children of an overlay, because it already exists
+	// in the overlay's base file system
+	implicit bool
+}
+
+// NewMemory creates an empty in-memory file system
+func NewMemory() *Memory {
+	return &Memory{
+		nodes: make(map[string]*node),
+	}
+}
+
+// source locates a file of another file system
+type source struct {
+	fsys Reader
+	path string
+}
+
+// fileInfo describes a node of the in-memory file system
+type fileInfo struct {
+	name string
+	node *node
+}
+
+func (i fileInfo) Name() string { return i.name }
+func (i fileInfo) Size() int64 {
+	if src := i.node.source; src != nil {
+		if info, err := src.fsys.Stat(src.path); err == nil {
+			return info.Size()
+		}
+	}
+	return int64(len(i.node.data))
+}
+
+func (i fileInfo) Mode() os.FileMode  { return i.node.mode }
+func (i fileInfo) ModTime() time.Time { return i.node.modTime }
+func (i fileInfo) IsDir() bool        { return i.node.mode.IsDir() }
+func (i fileInfo) Sys() interface{}   { return nil }
+
+// isRoot determines whether given clean path refers to a root or current dir, which always exist
+func isRoot(path string) bool {
+	return path == "." || path == string(filepath.Separator) || filepath.Dir(path) == path
+}
+
+// get returns the node at given clean path, without following symlinks
+func (m *Memory) get(path string) (*node, bool) {
+	if isRoot(path) {
+		return &node{mode: os.ModeDir | 0755}, true
+	}
+	n, ok := m.nodes[path]
+	return n, ok
+}
+
+// resolve follows symlinks of given clean path's last component and returns the final path and node
+func (m *Memory) resolve(op, path string) (string, *node, error) {
+	for i := 0; i < maxSymlinks; i++ {
+		n, ok := m.get(path)
+		if !ok {
+			return path, nil, &os.PathError{Op: op, Path: path, Err: os.ErrNotExist}
+		}
+		if n.mode&os.ModeSymlink == 0 {
+			return path, n, nil
+		}
+		target := n.link
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		path = filepath.Clean(target)
+	}
+	return path, nil, &os.PathError{Op: op, Path: path, Err: syscall.ELOOP}
+}
+
+// checkParent ensures the parent of given clean path exists and is a dir
+func (m *Memory) checkParent(op, path string) error {
+	parent, ok := m.get(filepath.Dir(path))
+	if !ok {
+		return &os.PathError{Op: op, Path: path, Err: os.ErrNotExist}
+	}
+	if !parent.mode.IsDir() {
+		return &os.PathError{Op: op, Path: path, Err: syscall.ENOTDIR}
+	}
+	return nil
+}
+
+func (m *Memory) ReadDir(dir string) ([]os.FileInfo, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	dir, n, err := m.resolve("readdir", filepath.Clean(dir))
+	if err != nil {
+		return nil, err
+	}
+	if !n.mode.IsDir() {
+		return nil, &os.PathError{Op: "readdir", Path: dir, Err: syscall.ENOTDIR}
+	}
+	var infos []os.FileInfo
+	for path, child := range m.nodes {
+		if filepath.Dir(path) == dir && path != dir {
+			infos = append(infos, fileInfo{name: filepath.Base(path), node: child})
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name() < infos[j].Name()
+	})
+	return infos, nil
+}
+
+// getFile returns the content of given file, or the source to read it from for files copied lazily
+func (m *Memory) getFile(path string) ([]byte, *source, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	path, n, err := m.resolve("open", filepath.Clean(path))
+	if err != nil {
+		return nil, nil, err
+	}
+	if n.mode.IsDir() {
+		return nil, nil, &os.PathError{Op: "read", Path: path, Err: syscall.EISDIR}
+	}
+	return append([]byte(nil), n.data...), n.source, nil
+}
+
+func (m *Memory) ReadFile(path string) ([]byte, error) {
+	data, src, err := m.getFile(path)
+	if err != nil || src == nil {
+		return data, err
+	}
+	return src.fsys.ReadFile(src.path)
+}
+
+func (m *Memory) Open(path string) (io.ReadCloser, error) {
+	data, src, err := m.getFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if src != nil {
+		return src.fsys.Open(src.path)
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *Memory) Stat(path string) (os.FileInfo, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	path = filepath.Clean(path)
+	_, n, err := m.resolve("stat", path)
+	if err != nil {
+		return nil, err
+	}
+	return fileInfo{name: filepath.Base(path), node: n}, nil
+}
+
+func (m *Memory) Lstat(path string) (os.FileInfo, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	path = filepath.Clean(path)
+	n, ok := m.get(path)
+	if !ok {
+		return nil, &os.PathError{Op: "lstat", Path: path, Err: os.ErrNotExist}
+	}
+	return fileInfo{name: filepath.Base(path), node: n}, nil
+}
+
+func (m *Memory) Readlink(path string) (string, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	path = filepath.Clean(path)
+	n, ok := m.get(path)
+	if !ok {
+		return "", &os.PathError{Op: "readlink", Path: path, Err: os.ErrNotExist}
+	}
+	if n.mode&os.ModeSymlink == 0 {
+		return "", &os.PathError{Op: "readlink", Path: path, Err: syscall.EINVAL}
+	}
+	return n.link, nil
+}
+
+func (m *Memory) WriteFile(path string, data []byte, mode os.FileMode) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	path, n, err := m.resolve("open", filepath.Clean(path))
+	if err == nil {
+		// Existing files keep their mode
+		if n.mode.IsDir() {
+			return &os.PathError{Op: "open", Path: path, Err: syscall.EISDIR}
+		}
+		n.data = append([]byte(nil), data...)
+		n.source = nil
+		n.modTime = time.Now()
+		return nil
+	}
+	if err := m.checkParent("open", path); err != nil {
+		return err
+	}
+	m.nodes[path] = &node{
+		mode:    mode.Perm(),
+		data:    append([]byte(nil), data...),
+		modTime: time.Now(),
+	}
+	return nil
+}
+
+func (m *Memory) Create(path string, mode os.FileMode) (io.WriteCloser, error) {
+	// Create file right away, so that errors are reported early
+	if err := m.WriteFile(path, nil, mode); err != nil {
+		return nil, err
+	}
+	return &writer{
+		close: func(data []byte) error {
+			return m.WriteFile(path, data, mode)
+		},
+	}, nil
+}
+
+// CopyFrom creates or replaces given file with a copy of given source file, whose content only gets read when needed
+func (m *Memory) CopyFrom(fsys Reader, sourcePath, path string, mode os.FileMode) error {
+	if err := m.WriteFile(path, nil, mode); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	_, n, err := m.resolve("open", filepath.Clean(path))
+	if err != nil {
+		return err
+	}
+	n.source = &source{fsys: fsys, path: sourcePath}
+	return nil
+}
+
+// writer buffers written content until closed
+type writer struct {
+	bytes.Buffer
+	close func(data []byte) error
+}
+
+func (w *writer) Close() error {
+	return w.close(w.Bytes())
+}
+
+func (m *Memory) MkdirAll(path string, mode os.FileMode) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.mkdirAll(filepath.Clean(path), mode, false)
+}
+
+// mkdirAll creates given clean dir path and its missing parents, flagging new dirs as implicit if requested
+func (m *Memory) mkdirAll(path string, mode os.FileMode, implicit bool) error {
+	if n, ok := m.get(path); ok {
+		if !n.mode.IsDir() {
+			return &os.PathError{Op: "mkdir", Path: path, Err: syscall.ENOTDIR}
+		}
+		return nil
+	}
+	if err := m.mkdirAll(filepath.Dir(path), mode, implicit); err != nil {
+		return err
+	}
+	m.nodes[path] = &node{
+		mode:     os.ModeDir | mode.Perm(),
+		modTime:  time.Now(),
+		implicit: implicit,
+	}
+	return nil
+}
+
+func (m *Memory) Chmod(path string, mode os.FileMode) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	_, n, err := m.resolve("chmod", filepath.Clean(path))
+	if err != nil {
+		return err
+	}
+	n.mode = n.mode&os.ModeType | mode.Perm()
+	n.implicit = false
+	return nil
+}
+
+func (m *Memory) Remove(path string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	path = filepath.Clean(path)
+	n, ok := m.get(path)
+	if !ok {
+		return &os.PathError{Op: "remove", Path: path, Err: os.ErrNotExist}
+	}
+	if n.mode.IsDir() {
+		for other := range m.nodes {
+			if filepath.Dir(other) == path && other != path {
+				return &os.PathError{Op: "remove", Path: path, Err: syscall.ENOTEMPTY}
+			}
+		}
+	}
+	delete(m.nodes, path)
+	return nil
+}
+
+func (m *Memory) Symlink(target, path string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	path = filepath.Clean(path)
+	if _, ok := m.get(path); ok {
+		return &os.LinkError{Op: "symlink", Old: target, New: path, Err: os.ErrExist}
+	}
+	if err := m.checkParent("symlink", path); err != nil {
+		return err
+	}
+	m.nodes[path] = &node{
+		mode:    os.ModeSymlink | 0777,
+		link:    target,
+		modTime: time.Now(),
+	}
+	return nil
+}
+
+// paths returns all paths of file system, sorted so that parents come before their children
+func (m *Memory) paths() []string {
+	paths := make([]string, 0, len(m.nodes))
+	for path := range m.nodes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,70 @@
+package vfs
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory(t *testing.T) {
+	fsys := NewMemory()
+
+	// Files require their parent dir to exist
+	err := fsys.WriteFile("/project/file.txt", []byte("content"), 0644)
+	assert.True(t, os.IsNotExist(err))
+
+	require.NoError(t, fsys.MkdirAll("/project/sub", 0755))
+	require.NoError(t, fsys.WriteFile("/project/file.txt", []byte("content"), 0644))
+	require.NoError(t, fsys.WriteFile("/project/sub/script.sh", []byte("#!/bin/sh"), 0755))
+	require.NoError(t, fsys.Symlink("file.txt", "/project/link.txt"))
+
+	// Read files back
+	data, err := fsys.ReadFile("/project/file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(data))
+
+	data, err = fsys.ReadFile("/project/link.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(data), "symlinks should be followed")
+
+	reader, err := fsys.Open("/project/sub/script.sh")
+	require.NoError(t, err)
+	data, err = ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "#!/bin/sh", string(data))
+
+	// Inspect entries
+	infos, err := fsys.ReadDir("/project")
+	require.NoError(t, err)
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	assert.Equal(t, []string{"file.txt", "link.txt", "sub"}, names)
+
+	info, err := fsys.Stat("/project/sub/script.sh")
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode())
+
+	info, err = fsys.Lstat("/project/link.txt")
+	require.NoError(t, err)
+	assert.NotZero(t, info.Mode()&os.ModeSymlink)
+	target, err := fsys.Readlink("/project/link.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "file.txt", target)
+
+	// Modify and remove entries
+	require.NoError(t, fsys.Chmod("/project/file.txt", 0600))
+	info, err = fsys.Stat("/project/file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode())
+
+	assert.Error(t, fsys.Remove("/project/sub"), "non-empty dirs cannot be removed")
+	require.NoError(t, fsys.Remove("/project/sub/script.sh"))
+	require.NoError(t, fsys.Remove("/project/sub"))
+	_, err = fsys.Lstat("/project/sub")
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,379 @@
+package vfs
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+)
+
+// Overlay is a file system that reads through to a base file system, but keeps all changes in memory until they get
+// committed to base file system in one pass, or discarded. That allows to preview changes or to abort a whole
+// operation midway, without leaving base file system in a partially modified state.
+type Overlay struct {
+	base  FileSystem
+	upper *Memory
+
+	// removed tracks paths of base file system that must be removed before applying changes
+	mutex   sync.Mutex
+	removed map[string]bool
+}
+
+// NewOverlay creates an overlay on top of given base file system
+func NewOverlay(base FileSystem) *Overlay {
+	return &Overlay{
+		base:    base,
+		upper:   NewMemory(),
+		removed: make(map[string]bool),
+	}
+}
+
+func (o *Overlay) isRemoved(path string) bool {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.removed[path]
+}
+
+// layer returns the file system holding current version of given clean path, or nil if it was removed
+func (o *Overlay) layer(path string) Reader {
+	if _, err := o.upper.Lstat(path); err == nil {
+		return o.upper
+	}
+	if o.isRemoved(path) {
+		return nil
+	}
+	return o.base
+}
+
+// resolve follows symlinks of given clean path's last component and returns the final path and its info
+func (o *Overlay) resolve(op, path string) (string, os.FileInfo, error) {
+	for i := 0; i < maxSymlinks; i++ {
+		info, err := o.Lstat(path)
+		if err != nil {
+			return path, nil, err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			return path, info, nil
+		}
+		target, err := o.Readlink(path)
+		if err != nil {
+			return path, nil, err
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		path = filepath.Clean(target)
+	}
+	return path, nil, &os.PathError{Op: op, Path: path, Err: syscall.ELOOP}
+}
+
+// ensureParent ensures the parent of given clean path exists as a dir and mirrors it in upper layer
+func (o *Overlay) ensureParent(op, path string) error {
+	parent := filepath.Dir(path)
+	info, err := o.Stat(parent)
+	if err != nil {
+		return &os.PathError{Op: op, Path: path, Err: os.ErrNotExist}
+	}
+	if !info.IsDir() {
+		return &os.PathError{Op: op, Path: path, Err: syscall.ENOTDIR}
+	}
+	o.upper.mutex.Lock()
+	defer o.upper.mutex.Unlock()
+	return o.upper.mkdirAll(parent, info.Mode().Perm(), true)
+}
+
+func (o *Overlay) ReadDir(dir string) ([]os.FileInfo, error) {
+	dir, info, err := o.resolve("readdir", filepath.Clean(dir))
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, &os.PathError{Op: "readdir", Path: dir, Err: syscall.ENOTDIR}
+	}
+
+	// Merge entries of both layers, with upper layer taking precedence
+	entries := make(map[string]os.FileInfo)
+	if !o.isRemoved(dir) {
+		if infos, err := o.base.ReadDir(dir); err == nil {
+			for _, info := range infos {
+				if !o.isRemoved(filepath.Join(dir, info.Name())) {
+					entries[info.Name()] = info
+				}
+			}
+		}
+	}
+	if infos, err := o.upper.ReadDir(dir); err == nil {
+		for _, info := range infos {
+			if existing, ok := entries[info.Name()]; ok && info.(fileInfo).node.implicit {
+				// Implicit dirs are only placeholders for their base counterparts
+				info = existing
+			}
+			entries[info.Name()] = info
+		}
+	}
+
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, info := range entries {
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name() < infos[j].Name()
+	})
+	return infos, nil
+}
+
+func (o *Overlay) ReadFile(path string) ([]byte, error) {
+	path, info, err := o.resolve("open", filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, &os.PathError{Op: "read", Path: path, Err: syscall.EISDIR}
+	}
+	return o.layer(path).ReadFile(path)
+}
+
+func (o *Overlay) Open(path string) (io.ReadCloser, error) {
+	path, info, err := o.resolve("open", filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, &os.PathError{Op: "read", Path: path, Err: syscall.EISDIR}
+	}
+	return o.layer(path).Open(path)
+}
+
+func (o *Overlay) Stat(path string) (os.FileInfo, error) {
+	_, info, err := o.resolve("stat", filepath.Clean(path))
+	return info, err
+}
+
+func (o *Overlay) Lstat(path string) (os.FileInfo, error) {
+	path = filepath.Clean(path)
+	layer := o.layer(path)
+	if layer == nil {
+		return nil, &os.PathError{Op: "lstat", Path: path, Err: os.ErrNotExist}
+	}
+	info, err := layer.Lstat(path)
+	if err == nil && layer == o.upper && info.(fileInfo).node.implicit {
+		// Implicit dirs are only placeholders for their base counterparts
+		if baseInfo, err := o.base.Lstat(path); err == nil {
+			return baseInfo, nil
+		}
+	}
+	return info, err
+}
+
+func (o *Overlay) Readlink(path string) (string, error) {
+	path = filepath.Clean(path)
+	layer := o.layer(path)
+	if layer == nil {
+		return "", &os.PathError{Op: "readlink", Path: path, Err: os.ErrNotExist}
+	}
+	return layer.Readlink(path)
+}
+
+func (o *Overlay) WriteFile(path string, data []byte, mode os.FileMode) error {
+	path, mode, err := o.prepareWrite(path, mode)
+	if err != nil {
+		return err
+	}
+	return o.upper.WriteFile(path, data, mode)
+}
+
+// CopyFrom creates or replaces given file with a copy of given source file, which only gets read when needed, so
+// that copied files are streamed upon commit rather than held in memory
+func (o *Overlay) CopyFrom(fsys Reader, sourcePath, path string, mode os.FileMode) error {
+	path, mode, err := o.prepareWrite(path, mode)
+	if err != nil {
+		return err
+	}
+	return o.upper.CopyFrom(fsys, sourcePath, path, mode)
+}
+
+// prepareWrite resolves given file path for writing and ensures its parent exists in upper layer, returning the
+// resolved path and the mode to create it with, which is that of existing file, if any
+func (o *Overlay) prepareWrite(path string, mode os.FileMode) (string, os.FileMode, error) {
+	path, info, err := o.resolve("open", filepath.Clean(path))
+	if err == nil {
+		if info.IsDir() {
+			return "", 0, &os.PathError{Op: "open", Path: path, Err: syscall.EISDIR}
+		}
+		// Existing files keep their mode
+		mode = info.Mode()
+	} else if !os.IsNotExist(err) {
+		return "", 0, err
+	}
+	if err := o.ensureParent("open", path); err != nil {
+		return "", 0, err
+	}
+	return path, mode.Perm(), nil
+}
+
+func (o *Overlay) Create(path string, mode os.FileMode) (io.WriteCloser, error) {
+	// Create file right away, so that errors are reported early
+	if err := o.WriteFile(path, nil, mode); err != nil {
+		return nil, err
+	}
+	return &writer{
+		close: func(data []byte) error {
+			return o.WriteFile(path, data, mode)
+		},
+	}, nil
+}
+
+func (o *Overlay) MkdirAll(path string, mode os.FileMode) error {
+	path = filepath.Clean(path)
+	info, err := o.Stat(path)
+	if err == nil {
+		if !info.IsDir() {
+			return &os.PathError{Op: "mkdir", Path: path, Err: syscall.ENOTDIR}
+		}
+		return nil
+	}
+	if !os.IsNotExist(err) {
+		return err
+	}
+	if err := o.MkdirAll(filepath.Dir(path), mode); err != nil {
+		return err
+	}
+	if err := o.ensureParent("mkdir", path); err != nil {
+		return err
+	}
+	return o.upper.MkdirAll(path, mode)
+}
+
+func (o *Overlay) Chmod(path string, mode os.FileMode) error {
+	path, info, err := o.resolve("chmod", filepath.Clean(path))
+	if err != nil {
+		return err
+	}
+
+	// Copy base file or dir into upper layer, in order to change its mode
+	if _, err := o.upper.Lstat(path); err != nil {
+		if err := o.ensureParent("chmod", path); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if err := o.upper.MkdirAll(path, mode); err != nil {
+				return err
+			}
+		} else {
+			// Base file only gets referenced, rather than read, as it does not need to be copied upon commit
+			if err := o.upper.CopyFrom(o.base, path, path, mode); err != nil {
+				return err
+			}
+		}
+	}
+	return o.upper.Chmod(path, mode)
+}
+
+func (o *Overlay) Remove(path string) error {
+	path = filepath.Clean(path)
+	info, err := o.Lstat(path)
+	if err != nil {
+		return &os.PathError{Op: "remove", Path: path, Err: os.ErrNotExist}
+	}
+	if info.IsDir() {
+		infos, err := o.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		if len(infos) > 0 {
+			return &os.PathError{Op: "remove", Path: path, Err: syscall.ENOTEMPTY}
+		}
+	}
+
+	if _, err := o.upper.Lstat(path); err == nil {
+		if err := o.upper.Remove(path); err != nil {
+			return err
+		}
+	}
+	if _, err := o.base.Lstat(path); err == nil {
+		o.mutex.Lock()
+		o.removed[path] = true
+		o.mutex.Unlock()
+	}
+	return nil
+}
+
+func (o *Overlay) Symlink(target, path string) error {
+	path = filepath.Clean(path)
+	if _, err := o.Lstat(path); err == nil {
+		return &os.LinkError{Op: "symlink", Old: target, New: path, Err: os.ErrExist}
+	}
+	if err := o.ensureParent("symlink", path); err != nil {
+		return err
+	}
+	return o.upper.Symlink(target, path)
+}
+
+// Commit applies all pending changes to base file system in one pass and resets overlay
+func (o *Overlay) Commit() error {
+	o.mutex.Lock()
+	removed := make([]string, 0, len(o.removed))
+	for path := range o.removed {
+		removed = append(removed, path)
+	}
+	o.mutex.Unlock()
+
+	// Remove deepest paths first
+	sort.Sort(sort.Reverse(sort.StringSlice(removed)))
+	for _, path := range removed {
+		if err := o.base.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %q: %w", path, err)
+		}
+	}
+
+	// Apply new and modified entries, parents first
+	upper := o.upper
+	upper.mutex.RLock()
+	defer upper.mutex.RUnlock()
+	for _, path := range upper.paths() {
+		n := upper.nodes[path]
+		mode := n.mode.Perm()
+		switch {
+		case n.mode.IsDir():
+			if n.implicit {
+				continue
+			}
+			if err := o.base.MkdirAll(path, mode); err != nil {
+				return fmt.Errorf("failed to create directory %q: %w", path, err)
+			}
+			if err := o.base.Chmod(path, mode); err != nil {
+				return fmt.Errorf("failed to set mode of directory %q: %w", path, err)
+			}
+		case n.mode&os.ModeSymlink != 0:
+			if err := o.base.Symlink(n.link, path); err != nil {
+				return fmt.Errorf("failed to create symlink %q: %w", path, err)
+			}
+		case n.source != nil:
+			// Base files whose mode changed are left as is, while copied files get streamed from their source
+			if n.source.fsys != o.base || n.source.path != path {
+				if err := CopyFile(n.source.fsys, n.source.path, o.base, path, mode); err != nil {
+					return fmt.Errorf("failed to write file %q: %w", path, err)
+				}
+			}
+			if err := o.base.Chmod(path, mode); err != nil {
+				return fmt.Errorf("failed to set mode of file %q: %w", path, err)
+			}
+		default:
+			if err := o.base.WriteFile(path, n.data, mode); err != nil {
+				return fmt.Errorf("failed to write file %q: %w", path, err)
+			}
+			if err := o.base.Chmod(path, mode); err != nil {
+				return fmt.Errorf("failed to set mode of file %q: %w", path, err)
+			}
+		}
+	}
+
+	o.upper = NewMemory()
+	o.mutex.Lock()
+	o.removed = make(map[string]bool)
+	o.mutex.Unlock()
+	return nil
+}
@@ -0,0 +1,118 @@
+package vfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverlay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jen_vfs_")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "existing.txt"), []byte("existing"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "obsolete.txt"), []byte("obsolete"), 0644))
+
+	fsys := NewOverlay(OS{})
+
+	// Apply changes to overlay
+	require.NoError(t, fsys.WriteFile(filepath.Join(dir, "existing.txt"), []byte("modified"), 0600))
+	require.NoError(t, fsys.Remove(filepath.Join(dir, "obsolete.txt")))
+	require.NoError(t, fsys.MkdirAll(filepath.Join(dir, "sub", "dir"), 0700))
+	require.NoError(t, fsys.WriteFile(filepath.Join(dir, "sub", "dir", "new.sh"), []byte("new"), 0644))
+	require.NoError(t, fsys.Chmod(filepath.Join(dir, "sub", "dir", "new.sh"), 0755))
+	require.NoError(t, fsys.Symlink("existing.txt", filepath.Join(dir, "link.txt")))
+
+	// Changes should be visible through overlay...
+	data, err := fsys.ReadFile(filepath.Join(dir, "existing.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "modified", string(data))
+	data, err = fsys.ReadFile(filepath.Join(dir, "link.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "modified", string(data))
+	_, err = fsys.Stat(filepath.Join(dir, "obsolete.txt"))
+	assert.True(t, os.IsNotExist(err))
+	infos, err := fsys.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	assert.Equal(t, []string{"existing.txt", "link.txt", "sub"}, names)
+
+	// ...but not on disk
+	data, err = ioutil.ReadFile(filepath.Join(dir, "existing.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "existing", string(data))
+	_, err = os.Stat(filepath.Join(dir, "obsolete.txt"))
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(dir, "sub"))
+	assert.True(t, os.IsNotExist(err))
+
+	// Commit changes to disk
+	require.NoError(t, fsys.Commit())
+
+	data, err = ioutil.ReadFile(filepath.Join(dir, "existing.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "modified", string(data))
+	info, err := os.Stat(filepath.Join(dir, "existing.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode(), "existing files should keep their mode")
+	_, err = os.Stat(filepath.Join(dir, "obsolete.txt"))
+	assert.True(t, os.IsNotExist(err))
+	info, err = os.Stat(filepath.Join(dir, "sub", "dir"))
+	require.NoError(t, err)
+	assert.Equal(t, os.ModeDir|0700, info.Mode())
+	info, err = os.Stat(filepath.Join(dir, "sub", "dir", "new.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode())
+	target, err := os.Readlink(filepath.Join(dir, "link.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "existing.txt", target)
+}
+
+func TestOverlayCopy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jen_vfs_")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "source.bin"), []byte("source"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "script.sh"), []byte("script"), 0644))
+
+	fsys := NewOverlay(OS{})
+
+	// Copy file and change mode of existing one
+	require.NoError(t, CopyFile(OS{}, filepath.Join(dir, "source.bin"), fsys, filepath.Join(dir, "copy.bin"), 0600))
+	require.NoError(t, fsys.Chmod(filepath.Join(dir, "script.sh"), 0755))
+
+	// Content should be visible through overlay
+	data, err := fsys.ReadFile(filepath.Join(dir, "copy.bin"))
+	require.NoError(t, err)
+	assert.Equal(t, "source", string(data))
+	info, err := fsys.Stat(filepath.Join(dir, "copy.bin"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(len("source")), info.Size())
+	data, err = fsys.ReadFile(filepath.Join(dir, "script.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, "script", string(data))
+
+	// Source only gets read upon commit
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "source.bin"), []byte("modified"), 0644))
+	require.NoError(t, fsys.Commit())
+
+	data, err = ioutil.ReadFile(filepath.Join(dir, "copy.bin"))
+	require.NoError(t, err)
+	assert.Equal(t, "modified", string(data))
+	info, err = os.Stat(filepath.Join(dir, "copy.bin"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode())
+	data, err = ioutil.ReadFile(filepath.Join(dir, "script.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, "script", string(data))
+	info, err = os.Stat(filepath.Join(dir, "script.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode())
+}
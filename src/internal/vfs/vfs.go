@@ -0,0 +1,140 @@
+package vfs
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// Reader represents the read-only side of a file system, such as the one templates are read from
+type Reader interface {
+	// ReadDir returns the entries of given dir, sorted by name
+	ReadDir(dir string) ([]os.FileInfo, error)
+
+	// ReadFile returns the whole content of given file
+	ReadFile(path string) ([]byte, error)
+
+	// Open opens given file for streaming its content
+	Open(path string) (io.ReadCloser, error)
+
+	// Stat returns information about given file or dir, following symlinks
+	Stat(path string) (os.FileInfo, error)
+
+	// Lstat returns information about given file or dir, without following symlinks
+	Lstat(path string) (os.FileInfo, error)
+
+	// Readlink returns the target of given symlink
+	Readlink(path string) (string, error)
+}
+
+// FileSystem represents a writable file system, such as the one projects are rendered into
+type FileSystem interface {
+	Reader
+
+	// WriteFile writes given content to given file, creating it with given mode if it does not exist
+	WriteFile(path string, data []byte, mode os.FileMode) error
+
+	// Create creates or truncates given file for streaming content into it
+	Create(path string, mode os.FileMode) (io.WriteCloser, error)
+
+	// MkdirAll creates given dir and all its missing parents with given mode
+	MkdirAll(path string, mode os.FileMode) error
+
+	// Chmod changes the permission bits of given file or dir
+	Chmod(path string, mode os.FileMode) error
+
+	// Remove removes given file, symlink or empty dir
+	Remove(path string) error
+
+	// Symlink creates a symlink at given path pointing to given target
+	Symlink(target, path string) error
+}
+
+// Copier is implemented by file systems that can copy files from another file system more efficiently than by
+// streaming their content, such as in-memory ones, which only need to keep track of the source file
+type Copier interface {
+	// CopyFrom creates or truncates given file with a copy of given file of given source file system, creating it
+	// with given mode if it does not exist
+	CopyFrom(fsys Reader, sourcePath, path string, mode os.FileMode) error
+}
+
+// CopyFile copies given file of input file system into given file of output file system, creating it with given mode
+// if it does not exist, either by streaming its content or by delegating to output file system if it is a Copier
+func CopyFile(inputFS Reader, inputPath string, outputFS FileSystem, outputPath string, mode os.FileMode) error {
+	if copier, ok := outputFS.(Copier); ok {
+		return copier.CopyFrom(inputFS, inputPath, outputPath, mode)
+	}
+
+	input, err := inputFS.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	output, err := outputFS.Create(outputPath, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(output, input); err != nil {
+		output.Close()
+		return err
+	}
+	return output.Close()
+}
+
+// OS is the file system of the operating system
+type OS struct{}
+
+func (OS) ReadDir(dir string) ([]os.FileInfo, error) {
+	return ioutil.ReadDir(dir)
+}
+
+func (OS) ReadFile(path string) ([]byte, error) {
+	return ioutil.ReadFile(path)
+}
+
+func (OS) Open(path string) (io.ReadCloser, error) {
+	return os.Open(path)
+}
+
+func (OS) Stat(path string) (os.FileInfo, error) {
+	return os.Stat(path)
+}
+
+func (OS) Lstat(path string) (os.FileInfo, error) {
+	return os.Lstat(path)
+}
+
+func (OS) Readlink(path string) (string, error) {
+	return os.Readlink(path)
+}
+
+func (OS) WriteFile(path string, data []byte, mode os.FileMode) error {
+	return ioutil.WriteFile(path, data, mode)
+}
+
+func (OS) Create(path string, mode os.FileMode) (io.WriteCloser, error) {
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+}
+
+func (OS) MkdirAll(path string, mode os.FileMode) error {
+	return os.MkdirAll(path, mode)
+}
+
+func (OS) Chmod(path string, mode os.FileMode) error {
+	return os.Chmod(path, mode)
+}
+
+func (OS) Remove(path string) error {
+	return os.Remove(path)
+}
+
+func (OS) Symlink(target, path string) error {
+	return os.Symlink(target, path)
+}
+
+// Exists determines whether given path exists in given file system, without following symlinks
+func Exists(fsys Reader, path string) bool {
+	_, err := fsys.Lstat(path)
+	return err == nil
+}